@@ -3,6 +3,7 @@ package avro
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"unsafe"
 )
 
@@ -123,6 +124,10 @@ func (rc *arrayCodec) Write(w *WriteBuf, p unsafe.Pointer) {
 	for i := range sh.Len {
 		cursor := unsafe.Add(sh.Data, uintptr(i)*rc.itemType.Size())
 		rc.itemCodec.Write(w, cursor)
+		if w.err != nil {
+			w.addErrorPath("[" + strconv.Itoa(i) + "]")
+			return
+		}
 	}
 
 	// Write a zero count to indicate the end of the array. This does appear to
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"sync"
 	"unsafe"
 )
@@ -13,6 +14,7 @@ import (
 // by AVRO encoders. It is not safe for concurrent use.
 type WriteBuf struct {
 	buf []byte
+	err error
 }
 
 // NewWriteBuf returns a new WriteBuf.
@@ -38,6 +40,70 @@ func (w *WriteBuf) Bytes() []byte {
 
 func (w *WriteBuf) Reset() {
 	w.buf = w.buf[:0]
+	w.err = nil
+}
+
+// SetError records that a value could not be encoded. Codec.Write has no error
+// return, so codecs call SetError instead. Only the first error is kept, and
+// the data written since is not valid AVRO.
+func (w *WriteBuf) SetError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Err returns the first error passed to SetError since the buffer was created
+// or Reset.
+func (w *WriteBuf) Err() error {
+	return w.err
+}
+
+// EncodeError is the error recorded in a WriteBuf when a value inside a record,
+// array or map can't be encoded. It says where in the value the problem is.
+type EncodeError struct {
+	// path holds the elements of the path to the value that could not be
+	// encoded, innermost first.
+	path []string
+	Err  error
+}
+
+// Path returns the path to the value that could not be encoded, e.g.
+// "address.lines[2]". Array indexes and map keys are shown in square brackets.
+func (e *EncodeError) Path() string {
+	var b strings.Builder
+	for i := len(e.path) - 1; i >= 0; i-- {
+		elem := e.path[i]
+		if b.Len() > 0 && !strings.HasPrefix(elem, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(elem)
+	}
+	return b.String()
+}
+
+func (e *EncodeError) Error() string {
+	return "field " + e.Path() + ": " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// addErrorPath adds elem to the start of the path of the error in the buffer.
+// elem is a field name, or an array index or map key in square brackets.
+func (w *WriteBuf) addErrorPath(elem string) {
+	ee, ok := w.err.(*EncodeError)
+	if !ok {
+		ee = &EncodeError{Err: w.err}
+		w.err = ee
+	}
+	ee.path = append(ee.path, elem)
+}
+
+// truncate discards data written after the first l bytes and clears any error.
+func (w *WriteBuf) truncate(l int) {
+	w.buf = w.buf[:l]
+	w.err = nil
 }
 
 func (w *WriteBuf) Len() int {
@@ -30,9 +30,60 @@ func Register(typ reflect.Type, f CodecBuildFunc) {
 // be nil, in which case we still need a codec to know how to skip over the
 // field
 func buildCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+	schema, err := resolveNames(schema)
+	if err != nil {
+		return nil, err
+	}
+	return newCodecBuilder().build(schema, typ, omit)
+}
+
+// buildWriteCodec builds a codec for writing values of type typ with the given
+// schema. Codecs for writing are stricter than codecs for reading: every field
+// of a record in the schema must have a matching struct field, a default, or
+// be nullable.
+func buildWriteCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	schema, err := resolveNames(schema)
+	if err != nil {
+		return nil, err
+	}
+	b := newCodecBuilder()
+	b.writing = true
+	return b.build(schema, typ, false)
+}
+
+// codecBuilder holds state while building the codecs for a schema. Schemas
+// passed to the builder must have had their names resolved by resolveNames,
+// so references to named types are replaced by the types themselves. Records
+// may be recursive, so we remember the codecs we've built for each record and
+// Go type, and re-use them rather than building them again.
+type codecBuilder struct {
+	records  map[recordKey]Codec
+	resolved map[resolvedRecordKey]Codec
+	// writing is set if the codecs are only used for writing
+	writing bool
+}
+
+type recordKey struct {
+	object *SchemaObject
+	typ    reflect.Type
+}
+
+type resolvedRecordKey struct {
+	writer, reader *SchemaObject
+	typ            reflect.Type
+}
+
+func newCodecBuilder() *codecBuilder {
+	return &codecBuilder{
+		records:  make(map[recordKey]Codec),
+		resolved: make(map[resolvedRecordKey]Codec),
+	}
+}
+
+func (b *codecBuilder) build(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	if schema.Type != "union" && schema.Type != "null" && typ != nil {
 		if typ.Kind() == reflect.Pointer {
-			return buildPointerCodec(schema, typ)
+			return b.buildPointerCodec(schema, typ)
 		}
 
 		registryMutex.RLock()
@@ -41,6 +92,10 @@ func buildCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 		if ok {
 			return cf(schema, typ, omit)
 		}
+
+		if typ.Kind() == reflect.Interface {
+			return b.buildInterfaceCodec(schema, typ)
+		}
 	}
 
 	switch schema.Type {
@@ -61,15 +116,15 @@ func buildCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	case "string":
 		return buildStringCodec(typ, omit)
 	case "record":
-		return buildRecordCodec(schema, typ)
+		return b.buildRecordCodec(schema, typ)
 	case "enum":
-		return nil, fmt.Errorf("enum not currently supported")
+		return buildEnumCodec(schema, typ, omit)
 	case "array":
-		return buildArrayCodec(schema, typ, omit)
+		return b.buildArrayCodec(schema, typ, omit)
 	case "map":
-		return BuildMapCodec(schema, typ, omit)
+		return b.buildMapCodec(schema, typ, omit)
 	case "union":
-		return buildUnionCodec(schema, typ, omit)
+		return b.buildUnionCodec(schema, typ, omit)
 	case "fixed":
 		return buildFixedCodec(schema, typ)
 	}
@@ -77,8 +132,8 @@ func buildCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	return nil, fmt.Errorf("%s not currently supported", schema.Type)
 }
 
-func buildPointerCodec(schema Schema, typ reflect.Type) (Codec, error) {
-	c, err := buildCodec(schema, typ.Elem(), false)
+func (b *codecBuilder) buildPointerCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	c, err := b.build(schema, typ.Elem(), false)
 	if err != nil {
 		return nil, err
 	}
@@ -94,27 +149,31 @@ func buildBoolCodec(typ reflect.Type, omit bool) (Codec, error) {
 }
 
 func buildIntCodec(typ reflect.Type, omit bool) (Codec, error) {
-	// We can actually use the same codecs as long ints. We might want to
-	// separate them if we do encoding.
-	return buildLongCodec(typ, omit)
+	// We use the same codecs as long ints, but check values are in range when
+	// writing.
+	return buildIntegerCodec(typ, omit, true)
 }
 
 func buildLongCodec(typ reflect.Type, omit bool) (Codec, error) {
+	return buildIntegerCodec(typ, omit, false)
+}
+
+func buildIntegerCodec(typ reflect.Type, omit bool, is32 bool) (Codec, error) {
 	// TODO: unsigned types?
 	// It's likely BQ will specify this type even for smaller integer types.
 	if typ == nil {
-		return Int64Codec{omitEmpty: omit}, nil
+		return Int64Codec{omitEmpty: omit, is32: is32}, nil
 	}
 
 	switch typ.Kind() {
 	case reflect.Uint64:
-		return Uint64Codec{omitEmpty: omit}, nil
+		return Uint64Codec{omitEmpty: omit, is32: is32}, nil
 	case reflect.Int64, reflect.Int:
-		return Int64Codec{omitEmpty: omit}, nil
+		return Int64Codec{omitEmpty: omit, is32: is32}, nil
 	case reflect.Int32:
-		return Int32Codec{omitEmpty: omit}, nil
+		return Int32Codec{omitEmpty: omit, is32: is32}, nil
 	case reflect.Int16:
-		return Int32Codec{omitEmpty: omit}, nil
+		return Int16Codec{omitEmpty: omit, is32: is32}, nil
 	}
 
 	return nil, fmt.Errorf("type %s (kind %s) not supported for long codec", typ, typ.Kind())
@@ -175,7 +234,7 @@ func buildStringCodec(typ reflect.Type, omit bool) (Codec, error) {
 	return StringCodec{omitEmpty: omit}, nil
 }
 
-func buildArrayCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+func (b *codecBuilder) buildArrayCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	var itemType reflect.Type
 	if typ != nil {
 		if typ.Kind() != reflect.Slice {
@@ -184,7 +243,7 @@ func buildArrayCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error)
 		itemType = typ.Elem()
 	}
 
-	itemCodec, err := buildCodec(schema.Object.Items, itemType, false)
+	itemCodec, err := b.build(schema.Object.Items, itemType, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not build array item codec: %w", err)
 	}
@@ -192,7 +251,17 @@ func buildArrayCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error)
 	return &arrayCodec{itemCodec: itemCodec, itemType: itemType, omitEmpty: omit}, nil
 }
 
+// BuildMapCodec builds a codec for an AVRO map schema. typ must be a map with
+// string keys, or nil.
 func BuildMapCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+	schema, err := resolveNames(schema)
+	if err != nil {
+		return nil, err
+	}
+	return newCodecBuilder().buildMapCodec(schema, typ, omit)
+}
+
+func (b *codecBuilder) buildMapCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	var valueType reflect.Type
 	if typ != nil {
 		if typ.Kind() != reflect.Map || typ.Key().Kind() != reflect.String {
@@ -201,7 +270,7 @@ func BuildMapCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 		valueType = typ.Elem()
 	}
 
-	valueCodec, err := buildCodec(schema.Object.Values, valueType, false)
+	valueCodec, err := b.build(schema.Object.Values, valueType, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not build map value codec: %w", err)
 	}
@@ -209,7 +278,23 @@ func BuildMapCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
 	return &MapCodec{valueCodec: valueCodec, rtype: typ, omitEmpty: omit}, nil
 }
 
-func buildUnionCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+func (b *codecBuilder) buildUnionCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+	if typ != nil {
+		if typ.Kind() == reflect.Interface {
+			return b.buildUnionInterfaceCodec(schema, typ)
+		}
+		if isUnionStruct(typ) {
+			return b.buildUnionStructCodec(schema, typ)
+		}
+		if typ.Kind() == reflect.Pointer && isUnionStruct(typ.Elem()) {
+			c, err := b.buildUnionStructCodec(schema, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return unionStructPointerCodec{unionStructCodec: c.(*unionStructCodec)}, nil
+		}
+	}
+
 	if len(schema.Union) == 2 {
 		if schema.Union[0].Type == "null" || schema.Union[1].Type == "null" {
 			var c unionOneAndNullCodec
@@ -217,7 +302,7 @@ func buildUnionCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error)
 				c.nonNull = 1
 			}
 			u := schema.Union[c.nonNull]
-			sc, err := buildCodec(u, typ, omit)
+			sc, err := b.build(u, typ, omit)
 			if err != nil {
 				return nil, fmt.Errorf("failed to build union sub-codec %q: %w", u.Type, err)
 			}
@@ -235,7 +320,7 @@ func buildUnionCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error)
 	// We're only really expecting unions that are unions of a thing and null,
 	// so we can only cope with pointers for now
 	for i, u := range schema.Union {
-		sc, err := buildCodec(u, typ, omit)
+		sc, err := b.build(u, typ, omit)
 		if err != nil {
 			return nil, fmt.Errorf("failed to build union sub-codec %q: %w", u.Type, err)
 		}
@@ -278,11 +363,98 @@ func omitEmpty(sf reflect.StructField) bool {
 	return false
 }
 
-func buildRecordCodec(schema Schema, typ reflect.Type) (Codec, error) {
+// avroTagOption returns the value of the named option in the avro struct tag of
+// sf. The tag holds comma separated name=value options, for example
+// `avro:"enum=RED|GREEN|BLUE"`.
+func avroTagOption(sf reflect.StructField, name string) (string, bool) {
+	opts := sf.Tag.Get("avro")
+	for len(opts) > 0 {
+		var opt string
+		if strings.HasPrefix(opts, "default=") {
+			// Defaults may contain commas, so a default is always the last
+			// option.
+			opt, opts = opts, ""
+		} else {
+			opt, opts, _ = strings.Cut(opts, ",")
+		}
+		key, value, _ := strings.Cut(opt, "=")
+		if key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// matchStructFields finds the struct field for each field of a record schema.
+// ntf maps names to struct fields. Fields match by name, then by the aliases
+// of the schema field, then by the aliases in the struct field's avro tag (e.g.
+// `avro:"alias=old|older"`). A struct field matches at most one schema field.
+// Matched fields are removed from ntf, and schema fields without a match have
+// a zero StructField.
+func matchStructFields(fields []SchemaRecordField, ntf map[string]reflect.StructField) []reflect.StructField {
+	matches := make([]reflect.StructField, len(fields))
+	if len(ntf) == 0 {
+		return matches
+	}
+
+	// Exact names take priority over any alias
+	for i, f := range fields {
+		if sf, ok := ntf[f.Name]; ok {
+			matches[i] = sf
+			delete(ntf, f.Name)
+		}
+	}
+
+	tagAliases := make(map[string]string)
+	for name, sf := range ntf {
+		if aliases, ok := avroTagOption(sf, "alias"); ok {
+			for _, alias := range strings.Split(aliases, "|") {
+				tagAliases[alias] = name
+			}
+		}
+	}
+
+	for i, f := range fields {
+		if matches[i].Type != nil {
+			continue
+		}
+		names := f.Aliases
+		if name, ok := tagAliases[f.Name]; ok {
+			names = append(names[:len(names):len(names)], name)
+		}
+		for _, name := range names {
+			if sf, ok := ntf[name]; ok {
+				matches[i] = sf
+				delete(ntf, name)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func (b *codecBuilder) buildRecordCodec(schema Schema, typ reflect.Type) (_ Codec, err error) {
 	if schema.Object == nil {
 		return nil, fmt.Errorf("record schema does not have object")
 	}
 
+	// If we're already building this record then it's recursive, and we
+	// return the codec we're building.
+	key := recordKey{object: schema.Object, typ: typ}
+	if c, ok := b.records[key]; ok {
+		return c, nil
+	}
+	defer func() {
+		if err != nil {
+			// Don't leave a partially built codec for others to use
+			delete(b.records, key)
+		}
+	}()
+
+	if typ != nil && typ.Kind() == reflect.Map {
+		return b.buildRecordMapCodec(schema, typ)
+	}
+
 	var ntf map[string]reflect.StructField
 	if typ != nil {
 		if typ.Kind() != reflect.Struct {
@@ -302,30 +474,70 @@ func buildRecordCodec(schema Schema, typ reflect.Type) (Codec, error) {
 		}
 	}
 
-	var rc recordCodec
-	rc.rtype = typ
+	rc := &recordCodec{rtype: typ}
+	b.records[key] = rc
+
+	matches := matchStructFields(schema.Object.Fields, ntf)
 
 	// The schema is in the driving-seat here
-	for _, schemaf := range schema.Object.Fields {
+	for i, schemaf := range schema.Object.Fields {
 		offset := uintptr(math.MaxUint64)
 		var fieldType reflect.Type
-		sf, ok := ntf[schemaf.Name]
-		if ok {
+		sf := matches[i]
+		var fill []byte
+		var fillErr error
+		if sf.Type != nil {
 			offset = sf.Offset
 			fieldType = sf.Type
+		} else if typ != nil {
+			// When writing we write the default for the field, or null if it
+			// is nullable.
+			fill, fillErr = unmappedFieldValue(schemaf)
+			if fillErr != nil && b.writing {
+				return nil, fmt.Errorf("schema field %q has no matching field in %s: %w", schemaf.Name, typ, fillErr)
+			}
 		}
 
-		codec, err := buildCodec(schemaf.Type, fieldType, omitEmpty(sf))
+		codec, err := b.build(schemaf.Type, fieldType, omitEmpty(sf))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get codec for field %q: %w", schemaf.Name, err)
 		}
 
 		rc.fields = append(rc.fields, recordCodecField{
-			codec:  codec,
-			offset: offset,
-			name:   schemaf.Name,
+			codec:   codec,
+			offset:  offset,
+			name:    schemaf.Name,
+			fill:    fill,
+			fillErr: fillErr,
 		})
 	}
 
-	return &rc, nil
+	// When reading, struct fields that aren't in the schema are set to their
+	// default, if they have one.
+	if len(ntf) > 0 && !b.writing {
+		for i := range typ.NumField() {
+			sf := typ.Field(i)
+			if _, ok := ntf[nameForField(sf)]; !ok {
+				continue
+			}
+			if _, ok := avroTagOption(sf, "default"); !ok {
+				continue
+			}
+			f, err := newSchemaBuilder().schemaForRecordField(typ, sf)
+			if err != nil {
+				return nil, fmt.Errorf("building schema for field %q: %w", sf.Name, err)
+			}
+			codec, err := b.buildDefaultCodec(f.Type, f.Default, sf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get default codec for field %q: %w", f.Name, err)
+			}
+			rc.fields = append(rc.fields, recordCodecField{
+				codec:  codec,
+				offset: sf.Offset,
+				name:   f.Name,
+			})
+		}
+	}
+
+	return rc, nil
 }
@@ -3,6 +3,7 @@ package avro
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -35,6 +36,31 @@ func SchemaForType(item any) (Schema, error) {
 	return schemaForType(typ)
 }
 
+// schemaBuilder holds state while building a schema for a Go type. Each named
+// type (record, enum or fixed) is defined once, and later uses of the type,
+// including recursive uses, refer to it by name.
+type schemaBuilder struct {
+	// defined maps the full names of the named types we've defined to their
+	// definitions.
+	defined map[string]definedType
+}
+
+// definedType is a named type defined by a schemaBuilder.
+type definedType struct {
+	// typ is the Go type the definition was built from. It is nil for enums,
+	// as the same enum may be used with several Go types.
+	typ    reflect.Type
+	schema Schema
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{defined: make(map[string]definedType)}
+}
+
+func schemaForType(typ reflect.Type) (Schema, error) {
+	return newSchemaBuilder().schemaForType(typ)
+}
+
 func isInSchemaRegistry(typ reflect.Type) (Schema, bool) {
 	schemaRegistryMutex.RLock()
 	defer schemaRegistryMutex.RUnlock()
@@ -42,9 +68,9 @@ func isInSchemaRegistry(typ reflect.Type) (Schema, bool) {
 	return s, ok
 }
 
-func schemaForType(typ reflect.Type) (Schema, error) {
+func (sb *schemaBuilder) schemaForType(typ reflect.Type) (Schema, error) {
 	if s, ok := isInSchemaRegistry(typ); ok {
-		return s, nil
+		return sb.registered(typ, s)
 	}
 
 	// BigQuery makes every basic type nullable. We'll send null for the zero
@@ -59,18 +85,29 @@ func schemaForType(typ reflect.Type) (Schema, error) {
 	case reflect.String:
 		return Schema{Type: "string"}, nil
 	case reflect.Struct:
-		return schemaForStruct(typ)
+		if isUnionStruct(typ) {
+			return sb.schemaForUnionStruct(typ)
+		}
+		return sb.schemaForStruct(typ)
 	case reflect.Array, reflect.Slice:
-		return schemaForArray(typ)
+		return sb.schemaForArray(typ)
 	case reflect.Map:
-		return schemaForMap(typ)
+		return sb.schemaForMap(typ)
 	case reflect.Pointer:
 		// If this is a pointer to a basic type then we don't need to wrap in a union as all the basic types are nullable.
-		underlying, err := schemaForType(typ.Elem())
+		underlying, err := sb.schemaForType(typ.Elem())
 		if err != nil {
 			return Schema{}, fmt.Errorf("getting underlying schema for pointer: %w", err)
 		}
-		if underlying.Type == "union" || underlying.Type == "array" || underlying.Type == "map" {
+		if underlying.Type == "union" {
+			// A nil pointer to a union struct is written as null, so the union
+			// needs a null branch.
+			if !slices.ContainsFunc(underlying.Union, func(s Schema) bool { return s.Type == "null" }) {
+				underlying.Union = append([]Schema{{Type: "null"}}, underlying.Union...)
+			}
+			return underlying, nil
+		}
+		if underlying.Type == "array" || underlying.Type == "map" {
 			return underlying, nil
 		}
 		return nullableSchema(underlying), nil
@@ -89,7 +126,58 @@ func nullableSchema(s Schema) Schema {
 	}
 }
 
-func schemaForStruct(typ reflect.Type) (Schema, error) {
+// registered returns the schema registered for typ. If the schema is for a
+// named type we define it the first time it is used and refer to it by name
+// after that.
+func (sb *schemaBuilder) registered(typ reflect.Type, s Schema) (Schema, error) {
+	switch s.Type {
+	case "record", "enum", "fixed":
+	default:
+		return s, nil
+	}
+	if s.Object == nil {
+		return s, nil
+	}
+	fullName := s.Object.FullName()
+	if def, ok := sb.defined[fullName]; ok {
+		if def.typ != typ {
+			return Schema{}, fmt.Errorf("the schema registered for %s defines %s, which is already defined for %s", typ, fullName, def.describe())
+		}
+		return Schema{Type: fullName}, nil
+	}
+	sb.defined[fullName] = definedType{typ: typ, schema: s}
+	return s, nil
+}
+
+// describe describes where a definition came from, for error messages.
+func (d definedType) describe() string {
+	if d.typ == nil {
+		return "an enum with symbols " + strings.Join(d.schema.Object.Symbols, "|")
+	}
+	return d.typ.String()
+}
+
+func (sb *schemaBuilder) schemaForStruct(typ reflect.Type) (Schema, error) {
+	// namespace must be a valid Avro namespace, which is a dot-separated
+	// alphanumeric string.
+	namespace := namespaceReplacer.Replace(typ.PkgPath())
+	var fullName string
+	if typ.Name() != "" {
+		fullName = typ.Name()
+		if namespace != "" {
+			fullName = namespace + "." + fullName
+		}
+		if def, ok := sb.defined[fullName]; ok {
+			if def.typ != typ {
+				return Schema{}, fmt.Errorf("%s and %s both have the name %s", typ, def.describe(), fullName)
+			}
+			return Schema{Type: fullName}, nil
+		}
+		// We record the definition before building the fields, so recursive
+		// uses of the type refer to it.
+		sb.defined[fullName] = definedType{typ: typ}
+	}
+
 	fields := make([]SchemaRecordField, 0, typ.NumField())
 	for i := range typ.NumField() {
 		field := typ.Field(i)
@@ -98,36 +186,201 @@ func schemaForStruct(typ reflect.Type) (Schema, error) {
 			continue
 		}
 
-		s, err := schemaForType(field.Type)
+		f, err := sb.schemaForRecordField(typ, field)
 		if err != nil {
 			return Schema{}, fmt.Errorf("getting schema for field %s: %w", name, err)
 		}
+		fields = append(fields, f)
+	}
+
+	s := Schema{
+		Type: "record",
+		Object: &SchemaObject{
+			Name:      typ.Name(),
+			Namespace: namespace,
+			Fields:    fields,
+		},
+	}
+	if fullName != "" {
+		sb.defined[fullName] = definedType{typ: typ, schema: s}
+	}
+	return s, nil
+}
+
+// schemaForUnionStruct builds a union schema for a struct that has a pointer
+// field for each branch of the union. The branches are in field order.
+func (sb *schemaBuilder) schemaForUnionStruct(typ reflect.Type) (Schema, error) {
+	var union []Schema
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, ok := avroTagOption(field, "branch")
+		if !ok {
+			continue
+		}
 
-		if omitEmpty(field) && s.Type != "union" {
-			s = nullableSchema(s)
+		switch name {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			union = append(union, Schema{Type: name})
+			continue
 		}
 
-		fields = append(fields, SchemaRecordField{
-			Name: name,
-			Type: s,
-		})
+		if field.Type.Kind() != reflect.Pointer {
+			return Schema{}, fmt.Errorf("field %s for union branch %q must be a pointer, not %s", field.Name, name, field.Type)
+		}
+		s, err := sb.schemaForType(field.Type.Elem())
+		if err != nil {
+			return Schema{}, fmt.Errorf("getting schema for union branch %s: %w", name, err)
+		}
+		// The branch may be a reference to a type defined earlier, which may
+		// be qualified by its namespace.
+		if branch := unionBranchName(s); branch != name && unqualifiedName(branch) != name {
+			return Schema{}, fmt.Errorf("field %s is for union branch %q but its type gives %q", field.Name, name, branch)
+		}
+		union = append(union, s)
 	}
 
-	return Schema{
-		Type: "record",
+	return Schema{Type: "union", Union: union}, nil
+}
+
+// schemaForRecordField builds the record field for a field of struct type
+// record, including any default given by an `avro:"default=..."` tag.
+func (sb *schemaBuilder) schemaForRecordField(record reflect.Type, field reflect.StructField) (SchemaRecordField, error) {
+	s, err := sb.schemaForField(record, field)
+	if err != nil {
+		return SchemaRecordField{}, err
+	}
+
+	if omitEmpty(field) && s.Type != "union" {
+		s = nullableSchema(s)
+	}
+
+	f := SchemaRecordField{
+		Name: nameForField(field),
+		Type: s,
+	}
+	if aliases, ok := avroTagOption(field, "alias"); ok {
+		f.Aliases = strings.Split(aliases, "|")
+	}
+
+	tag, ok := avroTagOption(field, "default")
+	if !ok {
+		return f, nil
+	}
+	// The default is checked against the definitions of any enums the field
+	// refers to by name.
+	expanded := sb.expand(s)
+	if f.Default, err = defaultFromTag(expanded, tag); err != nil {
+		return SchemaRecordField{}, fmt.Errorf("invalid default: %w", err)
+	}
+	if s.Type == "union" {
+		// The AVRO spec requires the default for a union to match the first
+		// branch, so move the branch the default matches to the front.
+		index, _, err := unionDefault(expanded, f.Default)
+		if err != nil {
+			return SchemaRecordField{}, err
+		}
+		union := make([]Schema, 0, len(s.Union))
+		union = append(union, s.Union[index])
+		union = append(union, s.Union[:index]...)
+		union = append(union, s.Union[index+1:]...)
+		f.Type = Schema{Type: "union", Union: union}
+	}
+	return f, nil
+}
+
+// expand replaces references to named types we've defined with their
+// definitions. It looks at s and, if s is a union, its branches.
+func (sb *schemaBuilder) expand(s Schema) Schema {
+	if def, ok := sb.defined[s.Type]; ok && def.schema.Type != "" {
+		return def.schema
+	}
+	if s.Type != "union" {
+		return s
+	}
+	union := make([]Schema, len(s.Union))
+	for i, u := range s.Union {
+		union[i] = sb.expand(u)
+	}
+	return Schema{Type: "union", Union: union}
+}
+
+func (sb *schemaBuilder) schemaForField(record reflect.Type, field reflect.StructField) (Schema, error) {
+	if symbols, ok := avroTagOption(field, "enum"); ok {
+		return sb.schemaForEnum(record, field, symbols)
+	}
+	return sb.schemaForType(field.Type)
+}
+
+// schemaForEnum builds an enum schema for a field tagged with the symbols of
+// the enum, e.g. `avro:"enum=RED|GREEN|BLUE"`. The field may be a string, an
+// integer holding the index of the symbol, or implement both
+// encoding.TextMarshaler and encoding.TextUnmarshaler so that the value can be
+// read back as well as written.
+func (sb *schemaBuilder) schemaForEnum(record reflect.Type, field reflect.StructField, symbols string) (Schema, error) {
+	typ := field.Type
+	nullable := typ.Kind() == reflect.Pointer
+	if nullable {
+		typ = typ.Elem()
+	}
+
+	ptr := reflect.PointerTo(typ)
+	if !ptr.Implements(textMarshalerType) || !ptr.Implements(textUnmarshalerType) {
+		switch typ.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return Schema{}, fmt.Errorf("type %s cannot be used for an enum", field.Type)
+		}
+	}
+
+	if symbols == "" {
+		return Schema{}, fmt.Errorf("enum tag for field %s has no symbols", field.Name)
+	}
+
+	// Enums must be named. If the field has its own named type we use that,
+	// otherwise we fall back to the name of the field. So that fields of the
+	// same name in different structs don't clash, the name is in a namespace
+	// named after the struct.
+	s := Schema{
+		Type: "enum",
 		Object: &SchemaObject{
-			Name: typ.Name(),
-			// namespace must be a valid Avro namespace, which is a
-			// dot-separated alphanumeric string.
-			Namespace: namespaceReplacer.Replace(typ.PkgPath()),
-			Fields:    fields,
+			Name:    field.Name,
+			Symbols: strings.Split(symbols, "|"),
 		},
-	}, nil
+	}
+	if record.Name() != "" {
+		s.Object.Namespace = record.Name()
+		if ns := namespaceReplacer.Replace(record.PkgPath()); ns != "" {
+			s.Object.Namespace = ns + "." + s.Object.Namespace
+		}
+	}
+	if typ.PkgPath() != "" {
+		s.Object.Name = typ.Name()
+		s.Object.Namespace = namespaceReplacer.Replace(typ.PkgPath())
+	}
+
+	// AVRO doesn't allow a name to be defined twice, so after the first use of
+	// an enum we refer to it by name.
+	fullName := s.Object.FullName()
+	if def, ok := sb.defined[fullName]; ok {
+		if def.typ != nil || !slices.Equal(def.schema.Object.Symbols, s.Object.Symbols) {
+			return Schema{}, fmt.Errorf("enum %s for field %s has symbols %v, but the name is already defined for %s", fullName, field.Name, s.Object.Symbols, def.describe())
+		}
+		s = Schema{Type: fullName}
+	} else {
+		sb.defined[fullName] = definedType{schema: s}
+	}
+
+	if nullable {
+		s = nullableSchema(s)
+	}
+	return s, nil
 }
 
 var namespaceReplacer = strings.NewReplacer("/", ".", "-", "_")
 
-func schemaForArray(typ reflect.Type) (Schema, error) {
+func (sb *schemaBuilder) schemaForArray(typ reflect.Type) (Schema, error) {
 	elem := typ.Elem()
 	if elem.Kind() == reflect.Uint8 {
 		return Schema{
@@ -135,7 +388,7 @@ func schemaForArray(typ reflect.Type) (Schema, error) {
 		}, nil
 	}
 
-	s, err := schemaForType(elem)
+	s, err := sb.schemaForType(elem)
 	if err != nil {
 		return Schema{}, fmt.Errorf("building array schema: %w", err)
 	}
@@ -148,8 +401,8 @@ func schemaForArray(typ reflect.Type) (Schema, error) {
 	}, nil
 }
 
-func schemaForMap(typ reflect.Type) (Schema, error) {
-	s, err := schemaForType(typ.Elem())
+func (sb *schemaBuilder) schemaForMap(typ reflect.Type) (Schema, error) {
+	s, err := sb.schemaForType(typ.Elem())
 	if err != nil {
 		return Schema{}, err
 	}
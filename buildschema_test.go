@@ -1,8 +1,10 @@
 package avro_test
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/go-json-experiment/json/jsontext"
 	"github.com/google/go-cmp/cmp"
 	"github.com/philpearl/avro"
 )
@@ -360,6 +362,110 @@ func TestBuildSchema(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "enum",
+			in: struct {
+				A string  `json:"aaa" avro:"enum=RED|GREEN"`
+				B *string `avro:"enum=UP|DOWN"`
+			}{},
+			exp: avro.Schema{
+				Type: "record",
+				Object: &avro.SchemaObject{
+					Fields: []avro.SchemaRecordField{
+						{
+							Name: "aaa",
+							Type: avro.Schema{
+								Type: "enum",
+								Object: &avro.SchemaObject{
+									Name:    "A",
+									Symbols: []string{"RED", "GREEN"},
+								},
+							},
+						},
+						{
+							Name: "B",
+							Type: avro.Schema{
+								Type: "union",
+								Union: []avro.Schema{
+									{Type: "null"},
+									{
+										Type: "enum",
+										Object: &avro.SchemaObject{
+											Name:    "B",
+											Symbols: []string{"UP", "DOWN"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "defaults",
+			in: struct {
+				A string   `json:"a" avro:"default=hello, world"`
+				B int      `json:"b" avro:"default=42"`
+				C *float64 `json:"c" avro:"default=1.5"`
+				D *string  `json:"d" avro:"alias=dd|ddd,default=null"`
+				E []int    `json:"e" avro:"default=[1,2]"`
+				F string   `json:"f" avro:"enum=X|Y,default=Y"`
+			}{},
+			exp: avro.Schema{
+				Type: "record",
+				Object: &avro.SchemaObject{
+					Fields: []avro.SchemaRecordField{
+						{
+							Name:    "a",
+							Type:    avro.Schema{Type: "string"},
+							Default: jsontext.Value(`"hello, world"`),
+						},
+						{
+							Name:    "b",
+							Type:    avro.Schema{Type: "long"},
+							Default: jsontext.Value(`42`),
+						},
+						{
+							Name: "c",
+							Type: avro.Schema{
+								Type:  "union",
+								Union: []avro.Schema{{Type: "double"}, {Type: "null"}},
+							},
+							Default: jsontext.Value(`1.5`),
+						},
+						{
+							Name: "d",
+							Type: avro.Schema{
+								Type:  "union",
+								Union: []avro.Schema{{Type: "null"}, {Type: "string"}},
+							},
+							Default: jsontext.Value(`null`),
+							Aliases: []string{"dd", "ddd"},
+						},
+						{
+							Name: "e",
+							Type: avro.Schema{
+								Type:   "array",
+								Object: &avro.SchemaObject{Items: avro.Schema{Type: "long"}},
+							},
+							Default: jsontext.Value(`[1,2]`),
+						},
+						{
+							Name: "f",
+							Type: avro.Schema{
+								Type: "enum",
+								Object: &avro.SchemaObject{
+									Name:    "F",
+									Symbols: []string{"X", "Y"},
+								},
+							},
+							Default: jsontext.Value(`"Y"`),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -374,3 +480,306 @@ func TestBuildSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSchemaBadDefault(t *testing.T) {
+	_, err := avro.SchemaForType(struct {
+		A int `json:"a" avro:"default=hat"`
+	}{})
+	if err == nil {
+		t.Fatal("expected an error for an invalid default")
+	}
+}
+
+type buildColour string
+
+func TestBuildSchemaEnumReuse(t *testing.T) {
+	type palette struct {
+		A buildColour  `json:"a" avro:"enum=RED|GREEN"`
+		B *buildColour `json:"b" avro:"enum=RED|GREEN"`
+		C buildColour  `json:"c" avro:"enum=RED|GREEN,default=GREEN"`
+	}
+
+	got, err := avro.SchemaForType(palette{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "github.com.philpearl.avro_test.buildColour"
+	exp := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name:      "palette",
+			Namespace: "github.com.philpearl.avro_test",
+			Fields: []avro.SchemaRecordField{
+				{
+					Name: "a",
+					Type: avro.Schema{
+						Type: "enum",
+						Object: &avro.SchemaObject{
+							Name:      "buildColour",
+							Namespace: "github.com.philpearl.avro_test",
+							Symbols:   []string{"RED", "GREEN"},
+						},
+					},
+				},
+				{
+					Name: "b",
+					Type: avro.Schema{
+						Type:  "union",
+						Union: []avro.Schema{{Type: "null"}, {Type: name}},
+					},
+				},
+				{
+					Name:    "c",
+					Type:    avro.Schema{Type: name},
+					Default: jsontext.Value(`"GREEN"`),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("schema not as expected (-want +got):\n%s", diff)
+	}
+
+	green := buildColour("GREEN")
+	in := palette{A: "RED", B: &green, C: "GREEN"}
+	data, err := avro.Marshal(got, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out palette
+	if err := avro.Unmarshal(got, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out); diff != "" {
+		t.Fatalf("round trip not as expected (-want +got):\n%s", diff)
+	}
+
+	type clash struct {
+		A buildColour `json:"a" avro:"enum=RED|GREEN"`
+		B buildColour `json:"b" avro:"enum=RED|BLUE"`
+	}
+	if _, err := avro.SchemaForType(clash{}); err == nil {
+		t.Fatal("expected an error for an enum redefined with different symbols")
+	}
+}
+
+type buildTrafficLight struct {
+	State string `json:"state" avro:"enum=RED|AMBER|GREEN"`
+}
+
+type buildSwitch struct {
+	State string `json:"state" avro:"enum=ON|OFF"`
+}
+
+type buildMarshalOnly struct{ v string }
+
+func (m buildMarshalOnly) MarshalText() ([]byte, error) { return []byte(m.v), nil }
+
+func TestBuildSchemaEnumFieldNames(t *testing.T) {
+	// Enums on fields without their own named type are named after the field,
+	// in a namespace named after the struct, so the same field name can be
+	// used in different structs.
+	type junction struct {
+		Light  buildTrafficLight `json:"light"`
+		Switch buildSwitch       `json:"switch"`
+	}
+	s, err := avro.SchemaForType(junction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range s.Object.Fields {
+		names = append(names, f.Type.Object.Fields[0].Type.Object.FullName())
+	}
+	want := []string{
+		"github.com.philpearl.avro_test.buildTrafficLight.State",
+		"github.com.philpearl.avro_test.buildSwitch.State",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Fatalf("enum names not as expected (-want +got):\n%s", diff)
+	}
+
+	in := junction{Light: buildTrafficLight{State: "AMBER"}, Switch: buildSwitch{State: "OFF"}}
+	data, err := avro.Marshal(s, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out junction
+	if err := avro.Unmarshal(s, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out); diff != "" {
+		t.Fatalf("round trip not as expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildSchemaEnumMarshalOnly(t *testing.T) {
+	// A type that can be written as text but not read back can't be used for
+	// an enum.
+	type record struct {
+		A buildMarshalOnly `json:"a" avro:"enum=X|Y"`
+	}
+	if _, err := avro.SchemaForType(record{}); err == nil {
+		t.Fatal("expected an error for an enum type that only implements encoding.TextMarshaler")
+	}
+}
+
+type buildHash [4]byte
+
+func TestBuildSchemaNamedTypes(t *testing.T) {
+	avro.RegisterSchema(reflect.TypeFor[buildHash](), avro.Schema{
+		Type:   "fixed",
+		Object: &avro.SchemaObject{Name: "Hash", Namespace: "com.acme", Size: 4},
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		type hashes struct {
+			A buildHash  `json:"a"`
+			B *buildHash `json:"b"`
+		}
+		got, err := avro.SchemaForType(hashes{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := got.Object.Fields
+		if fields[0].Type.Type != "fixed" {
+			t.Errorf("first use of the fixed is %#v", fields[0].Type)
+		}
+		if diff := cmp.Diff(avro.Schema{Type: "union", Union: []avro.Schema{{Type: "null"}, {Type: "com.acme.Hash"}}}, fields[1].Type); diff != "" {
+			t.Errorf("second use of the fixed not as expected (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("name clash", func(t *testing.T) {
+		// Types declared in different functions can have the same name and
+		// package.
+		record := func() any {
+			type dup struct {
+				A int64 `json:"a"`
+			}
+			return dup{}
+		}()
+		other := func() any {
+			type dup struct {
+				B string `json:"b"`
+			}
+			return dup{}
+		}()
+		enum := func() any {
+			type dup string
+			return dup("")
+		}()
+
+		tests := []struct {
+			name   string
+			fields []reflect.StructField
+		}{
+			{
+				name: "records",
+				fields: []reflect.StructField{
+					{Name: "A", Type: reflect.TypeOf(record), Tag: `json:"a"`},
+					{Name: "B", Type: reflect.TypeOf(other), Tag: `json:"b"`},
+				},
+			},
+			{
+				name: "record and enum",
+				fields: []reflect.StructField{
+					{Name: "A", Type: reflect.TypeOf(record), Tag: `json:"a"`},
+					{Name: "B", Type: reflect.TypeOf(enum), Tag: `json:"b" avro:"enum=RED|GREEN"`},
+				},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				typ := reflect.StructOf(test.fields)
+				if _, err := avro.SchemaForType(reflect.New(typ).Elem().Interface()); err == nil {
+					t.Fatal("expected an error for two types with the same name")
+				}
+			})
+		}
+	})
+}
+
+type recursiveNode struct {
+	Value    int64           `json:"value"`
+	Next     *recursiveNode  `json:"next"`
+	Children []recursiveNode `json:"children"`
+	Leaf     recursiveLeaf   `json:"leaf"`
+	Leaves   []recursiveLeaf `json:"leaves"`
+}
+
+type recursiveLeaf struct {
+	Name string `json:"name"`
+}
+
+func TestBuildSchemaRecursive(t *testing.T) {
+	got, err := avro.SchemaForType(recursiveNode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name:      "recursiveNode",
+			Namespace: "github.com.philpearl.avro_test",
+			Fields: []avro.SchemaRecordField{
+				{Name: "value", Type: avro.Schema{Type: "long"}},
+				{
+					Name: "next",
+					Type: avro.Schema{
+						Type: "union",
+						Union: []avro.Schema{
+							{Type: "null"},
+							{Type: "github.com.philpearl.avro_test.recursiveNode"},
+						},
+					},
+				},
+				{
+					Name: "children",
+					Type: avro.Schema{
+						Type:   "array",
+						Object: &avro.SchemaObject{Items: avro.Schema{Type: "github.com.philpearl.avro_test.recursiveNode"}},
+					},
+				},
+				{
+					Name: "leaf",
+					Type: avro.Schema{
+						Type: "record",
+						Object: &avro.SchemaObject{
+							Name:      "recursiveLeaf",
+							Namespace: "github.com.philpearl.avro_test",
+							Fields: []avro.SchemaRecordField{
+								{Name: "name", Type: avro.Schema{Type: "string"}},
+							},
+						},
+					},
+				},
+				{
+					Name: "leaves",
+					Type: avro.Schema{
+						Type:   "array",
+						Object: &avro.SchemaObject{Items: avro.Schema{Type: "github.com.philpearl.avro_test.recursiveLeaf"}},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("schema not as expected (-want +got):\n%s", diff)
+	}
+
+	// The schema should survive a round trip through JSON
+	data, err := got.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := avro.SchemaFromString(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, again); diff != "" {
+		t.Fatalf("schema changed in round trip (-want +got):\n%s", diff)
+	}
+}
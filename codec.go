@@ -2,13 +2,48 @@
 // output from Google's Big Query. It encodes directly from Go structs and
 // decodes directly into Go structs, and uses json tags as naming hints.
 //
+// Fields may also have an avro tag carrying extra schema information. For
+// example `avro:"enum=RED|GREEN|BLUE"` marks a string or integer field as an
+// AVRO enum with the given symbols, `avro:"alias=old|older"` lists other names
+// the field may have in data being read, and `avro:"default=..."` gives the
+// field a default value. Defaults for strings, bytes and enums are given
+// literally and other defaults as JSON. As a default may contain commas it must
+// be the last option in the tag. Fields missing from the data being read are
+// set to their default.
+//
+// Unions of more than null and one other type can be represented by an
+// interface field, or by a struct with a pointer field for each branch tagged
+// with the branch name, e.g. `avro:"branch=long"`. A pointer to such a struct
+// is nil for the null branch. When encoding an interface the dynamic type of
+// the value selects the branch. When decoding into an interface each value is
+// decoded into a natural Go type: bool, int32, int64, float32, float64, []byte
+// or string for primitives, string for enums, a byte array for fixed, []any for
+// arrays, and map[string]any for maps and records.
+//
 // The primary decoding interface is ReadFile. This reads an AVRO file,
 // combining the schema in the file with type information from the struct passed
 // via the out parameter to decode the records. It then passes an instance of a
-// struct of type out to the callback cb for each record in the file.
+// struct of type out to the callback cb for each record in the file. Pass
+// WithReaderSchema or WithSchemaResolution to read files written with a
+// different version of the schema using AVRO schema resolution. Alternatively
+// NewFileReader returns a FileReader, from which the application can pull
+// records and also see the file header.
 //
 // Use an Encoder to write AVRO files. Create an Encoder using NewEncoderFor, then
-// call Encode to write a record, and finally call Flush before closing the file.
+// call Encode to write a record, and finally call Close, or call Flush before
+// closing the file.
+//
+// NewEncoderForSchema creates an Encoder that writes with a given schema rather
+// than one derived from the Go type. A ConcurrentEncoder accepts records from
+// many goroutines and compresses blocks in parallel, and a RollingEncoder
+// splits its output across many files.
+//
+// SingleObjectEncoder and SingleObjectDecoder encode and decode individual
+// records using AVRO single-object encoding, where each record is prefixed with
+// the fingerprint of its schema. The decoder finds the writer's schema in a
+// SchemaStore.
+//
+// JSONEncoder and JSONDecoder write and read records in the AVRO JSON encoding.
 //
 // You can implement custom decoders for your own types and register them via
 // the Register function. github.com/phil/avro/null is an example of custom
@@ -38,5 +73,6 @@ type Codec interface {
 	Omit(p unsafe.Pointer) bool
 
 	// Write writes the wire format bytes for the value that p points to to w.
+	// If the value can't be written Write should call w.SetError and return.
 	Write(w *WriteBuf, p unsafe.Pointer)
 }
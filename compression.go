@@ -0,0 +1,332 @@
+package avro
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	dsnetbzip2 "github.com/dsnet/compress/bzip2"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// CompressionCodec compresses and decompresses the blocks of an AVRO file. Each
+// CompressionCodec is only used by one goroutine at a time, so it can re-use
+// buffers: the data returned by Compress and Decompress only needs to be valid
+// until the next call to either.
+type CompressionCodec interface {
+	Compress(uncompressed []byte) ([]byte, error)
+	Decompress(compressed []byte) ([]byte, error)
+}
+
+// CompressionFactory creates a CompressionCodec. The level is the compression
+// level set with WithCompressionLevel, or DefaultCompressionLevel. It should
+// return an error if the level is not valid for the codec.
+type CompressionFactory func(level int) (CompressionCodec, error)
+
+// DefaultCompressionLevel selects the default compression level for the
+// compression codec.
+const DefaultCompressionLevel = -1
+
+var (
+	compressionMutex    sync.RWMutex
+	compressionRegistry = map[Compression]CompressionFactory{
+		CompressionNull: func(level int) (CompressionCodec, error) {
+			return nullCompression{}, nil
+		},
+		CompressionDeflate: NewDeflateCodec,
+		CompressionSnappy: func(level int) (CompressionCodec, error) {
+			return NewSnappyCodec(true), nil
+		},
+		CompressionZstandard: NewZstandardCodec,
+		CompressionBzip2:     NewBzip2Codec,
+		CompressionXZ:        NewXZCodec,
+	}
+)
+
+// RegisterCompression makes a compression codec available for reading and
+// writing AVRO files. The name is the name of the codec in the avro.codec
+// field of the file header. Registering a factory for one of the built-in
+// codecs replaces it, so, for example, you can register a factory that calls
+// NewSnappyCodec(false) to stop the snappy codec re-using buffers.
+func RegisterCompression(name Compression, factory CompressionFactory) {
+	compressionMutex.Lock()
+	defer compressionMutex.Unlock()
+	compressionRegistry[name] = factory
+}
+
+// newCompressionCodec returns a CompressionCodec for the named compression.
+// The level is only used when compressing.
+func newCompressionCodec(compression Compression, level int) (CompressionCodec, error) {
+	compressionMutex.RLock()
+	factory, ok := compressionRegistry[compression]
+	compressionMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("compression codec %s not supported", compression)
+	}
+	c, err := factory(level)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s compression codec: %w", compression, err)
+	}
+	return c, nil
+}
+
+type nullCompression struct{}
+
+func (nullCompression) Decompress(compressed []byte) ([]byte, error) {
+	return compressed, nil
+}
+
+func (nullCompression) Compress(uncompressed []byte) ([]byte, error) {
+	return uncompressed, nil
+}
+
+type deflate struct {
+	level  int
+	reader io.Reader
+	writer *flate.Writer
+	buf    bytes.Reader
+	out    bytes.Buffer
+}
+
+// NewDeflateCodec returns a CompressionCodec for the deflate codec. The level
+// is as for compress/flate, from -2 (Huffman only) to 9.
+func NewDeflateCodec(level int) (CompressionCodec, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("deflate compression level %d out of range %d to %d", level, flate.HuffmanOnly, flate.BestCompression)
+	}
+	return &deflate{level: level}, nil
+}
+
+func (d *deflate) Decompress(compressed []byte) ([]byte, error) {
+	d.buf.Reset(compressed)
+	if d.reader == nil {
+		d.reader = flate.NewReader(nil)
+	}
+	d.reader.(flate.Resetter).Reset(&d.buf, nil)
+
+	d.out.Reset()
+	if _, err := d.out.ReadFrom(d.reader); err != nil {
+		return nil, fmt.Errorf("deflate decode failed: %w", err)
+	}
+
+	return d.out.Bytes(), nil
+}
+
+func (d *deflate) Compress(uncompressed []byte) ([]byte, error) {
+	d.out.Reset()
+	if d.writer == nil {
+		var err error
+		if d.writer, err = flate.NewWriter(&d.out, d.level); err != nil {
+			return nil, fmt.Errorf("creating deflate compressor: %w", err)
+		}
+	}
+	d.writer.Reset(&d.out)
+	if _, err := d.writer.Write(uncompressed); err != nil {
+		return nil, fmt.Errorf("writing to deflate compressor: %w", err)
+	}
+	if err := d.writer.Close(); err != nil {
+		return nil, fmt.Errorf("flushing deflate compressor: %w", err)
+	}
+
+	return d.out.Bytes(), nil
+}
+
+type snappyCodec struct {
+	reuse bool
+	buf   []byte
+}
+
+// NewSnappyCodec returns a CompressionCodec for the snappy codec. If reuse is
+// true the codec re-uses a buffer for its output, so the data it returns is
+// only valid until it is next called. Otherwise it allocates new memory for
+// each call.
+func NewSnappyCodec(reuse bool) CompressionCodec {
+	return &snappyCodec{reuse: reuse}
+}
+
+func (s *snappyCodec) Decompress(compressed []byte) ([]byte, error) {
+	if len(compressed) < 4 {
+		return nil, fmt.Errorf("snappy block too short (%d bytes)", len(compressed))
+	}
+	if !s.reuse {
+		s.buf = nil
+	}
+	var err error
+	s.buf, err = snappy.Decode(s.buf[:cap(s.buf)], compressed[:len(compressed)-4])
+	if err != nil {
+		return nil, fmt.Errorf("snappy decode failed: %w", err)
+	}
+
+	crc := binary.BigEndian.Uint32(compressed[len(compressed)-4:])
+	if crc32.ChecksumIEEE(s.buf) != crc {
+		return nil, errors.New("snappy checksum mismatch")
+	}
+
+	return s.buf, nil
+}
+
+func (s *snappyCodec) Compress(uncompressed []byte) ([]byte, error) {
+	if !s.reuse {
+		s.buf = nil
+	}
+	s.buf = snappy.Encode(s.buf[:cap(s.buf)], uncompressed)
+	crc := crc32.ChecksumIEEE(uncompressed)
+	s.buf = binary.BigEndian.AppendUint32(s.buf, crc)
+
+	return s.buf, nil
+}
+
+type zstdCodec struct {
+	level   int
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	buf     []byte
+}
+
+// NewZstandardCodec returns a CompressionCodec for the zstandard codec. The
+// level is from 1 to 22.
+func NewZstandardCodec(level int) (CompressionCodec, error) {
+	if level != DefaultCompressionLevel && (level < 1 || level > 22) {
+		return nil, fmt.Errorf("zstandard compression level %d out of range 1 to 22", level)
+	}
+	return &zstdCodec{level: level}, nil
+}
+
+func (z *zstdCodec) Decompress(compressed []byte) ([]byte, error) {
+	if z.decoder == nil {
+		var err error
+		if z.decoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+			return nil, fmt.Errorf("creating zstandard decoder: %w", err)
+		}
+	}
+	var err error
+	if z.buf, err = z.decoder.DecodeAll(compressed, z.buf[:0]); err != nil {
+		return nil, fmt.Errorf("zstandard decode failed: %w", err)
+	}
+	return z.buf, nil
+}
+
+func (z *zstdCodec) Compress(uncompressed []byte) ([]byte, error) {
+	if z.encoder == nil {
+		level := zstd.SpeedDefault
+		if z.level != DefaultCompressionLevel {
+			level = zstd.EncoderLevelFromZstd(z.level)
+		}
+		var err error
+		if z.encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1)); err != nil {
+			return nil, fmt.Errorf("creating zstandard encoder: %w", err)
+		}
+	}
+	z.buf = z.encoder.EncodeAll(uncompressed, z.buf[:0])
+	return z.buf, nil
+}
+
+type bzip2Codec struct {
+	level  int
+	writer *dsnetbzip2.Writer
+	buf    bytes.Reader
+	out    bytes.Buffer
+}
+
+// NewBzip2Codec returns a CompressionCodec for the bzip2 codec. The level is
+// from 1 to 9.
+func NewBzip2Codec(level int) (CompressionCodec, error) {
+	if level != DefaultCompressionLevel && (level < 1 || level > 9) {
+		return nil, fmt.Errorf("bzip2 compression level %d out of range 1 to 9", level)
+	}
+	return &bzip2Codec{level: level}, nil
+}
+
+func (b *bzip2Codec) Decompress(compressed []byte) ([]byte, error) {
+	b.buf.Reset(compressed)
+	b.out.Reset()
+	if _, err := b.out.ReadFrom(bzip2.NewReader(&b.buf)); err != nil {
+		return nil, fmt.Errorf("bzip2 decode failed: %w", err)
+	}
+	return b.out.Bytes(), nil
+}
+
+func (b *bzip2Codec) Compress(uncompressed []byte) ([]byte, error) {
+	b.out.Reset()
+	if b.writer == nil {
+		level := dsnetbzip2.DefaultCompression
+		if b.level != DefaultCompressionLevel {
+			level = b.level
+		}
+		var err error
+		if b.writer, err = dsnetbzip2.NewWriter(&b.out, &dsnetbzip2.WriterConfig{Level: level}); err != nil {
+			return nil, fmt.Errorf("creating bzip2 compressor: %w", err)
+		}
+	} else if err := b.writer.Reset(&b.out); err != nil {
+		return nil, fmt.Errorf("resetting bzip2 compressor: %w", err)
+	}
+	if _, err := b.writer.Write(uncompressed); err != nil {
+		return nil, fmt.Errorf("writing to bzip2 compressor: %w", err)
+	}
+	if err := b.writer.Close(); err != nil {
+		return nil, fmt.Errorf("flushing bzip2 compressor: %w", err)
+	}
+	return b.out.Bytes(), nil
+}
+
+type xzCodec struct {
+	level int
+	buf   bytes.Reader
+	out   bytes.Buffer
+}
+
+// NewXZCodec returns a CompressionCodec for the xz codec. The level is from 0
+// to 9, and sets the dictionary size as for the xz tool.
+func NewXZCodec(level int) (CompressionCodec, error) {
+	if level != DefaultCompressionLevel && (level < 0 || level > 9) {
+		return nil, fmt.Errorf("xz compression level %d out of range 0 to 9", level)
+	}
+	return &xzCodec{level: level}, nil
+}
+
+// xzDictCaps are the dictionary sizes used by the xz tool for compression
+// levels 0 to 9.
+var xzDictCaps = [10]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+func (x *xzCodec) Decompress(compressed []byte) ([]byte, error) {
+	x.buf.Reset(compressed)
+	r, err := xz.NewReader(&x.buf)
+	if err != nil {
+		return nil, fmt.Errorf("xz decode failed: %w", err)
+	}
+	x.out.Reset()
+	if _, err := x.out.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("xz decode failed: %w", err)
+	}
+	return x.out.Bytes(), nil
+}
+
+func (x *xzCodec) Compress(uncompressed []byte) ([]byte, error) {
+	var cfg xz.WriterConfig
+	if x.level != DefaultCompressionLevel {
+		cfg.DictCap = xzDictCaps[x.level]
+	}
+	x.out.Reset()
+	w, err := cfg.NewWriter(&x.out)
+	if err != nil {
+		return nil, fmt.Errorf("creating xz compressor: %w", err)
+	}
+	if _, err := io.Copy(w, bytes.NewReader(uncompressed)); err != nil {
+		return nil, fmt.Errorf("writing to xz compressor: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("flushing xz compressor: %w", err)
+	}
+	return x.out.Bytes(), nil
+}
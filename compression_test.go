@@ -0,0 +1,169 @@
+package avro
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompressionCodecs(t *testing.T) {
+	data := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 1000))
+
+	tests := []struct {
+		compression Compression
+		levels      []int
+	}{
+		{compression: CompressionNull, levels: []int{DefaultCompressionLevel}},
+		{compression: CompressionDeflate, levels: []int{DefaultCompressionLevel, -2, 1, 9}},
+		{compression: CompressionSnappy, levels: []int{DefaultCompressionLevel}},
+		{compression: CompressionZstandard, levels: []int{DefaultCompressionLevel, 1, 3, 22}},
+		{compression: CompressionBzip2, levels: []int{DefaultCompressionLevel, 1, 9}},
+		{compression: CompressionXZ, levels: []int{DefaultCompressionLevel, 0, 9}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.compression), func(t *testing.T) {
+			for _, level := range test.levels {
+				c, err := newCompressionCodec(test.compression, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Use the codec twice to check it can be re-used
+				for range 2 {
+					compressed, err := c.Compress(data)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if test.compression != CompressionNull && len(compressed) >= len(data) {
+						t.Errorf("level %d: compressed data is %d bytes, original %d", level, len(compressed), len(data))
+					}
+					// Compressors may re-use their buffers
+					compressed = bytes.Clone(compressed)
+
+					d, err := newCompressionCodec(test.compression, DefaultCompressionLevel)
+					if err != nil {
+						t.Fatal(err)
+					}
+					out, err := d.Decompress(compressed)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(data, out) {
+						t.Fatalf("level %d: data does not match after decompression", level)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompressionLevelErrors(t *testing.T) {
+	tests := []struct {
+		compression Compression
+		level       int
+	}{
+		{compression: CompressionDeflate, level: -3},
+		{compression: CompressionDeflate, level: 10},
+		{compression: CompressionZstandard, level: 0},
+		{compression: CompressionZstandard, level: 23},
+		{compression: CompressionBzip2, level: 0},
+		{compression: CompressionBzip2, level: 10},
+		{compression: CompressionXZ, level: 10},
+		{compression: "lz4", level: DefaultCompressionLevel},
+	}
+	for _, test := range tests {
+		if _, err := NewFileWriter([]byte(`"int"`), test.compression, WithCompressionLevel(test.level)); err == nil {
+			t.Errorf("%s level %d: expected an error", test.compression, test.level)
+		}
+	}
+}
+
+func TestSnappyNoReuse(t *testing.T) {
+	c := NewSnappyCodec(false)
+	first, err := c.Compress([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstCopy := bytes.Clone(first)
+	if _, err := c.Compress([]byte("goodbye")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, firstCopy) {
+		t.Fatal("compressed data overwritten by subsequent call")
+	}
+
+	out, err := c.Decompress(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+// xorCodec is a toy compression codec for testing RegisterCompression
+type xorCodec struct {
+	key byte
+	buf []byte
+}
+
+func (x *xorCodec) Compress(uncompressed []byte) ([]byte, error) {
+	x.buf = x.buf[:0]
+	for _, b := range uncompressed {
+		x.buf = append(x.buf, b^x.key)
+	}
+	return x.buf, nil
+}
+
+func (x *xorCodec) Decompress(compressed []byte) ([]byte, error) {
+	return x.Compress(compressed)
+}
+
+func TestRegisterCompression(t *testing.T) {
+	const name Compression = "test-xor"
+	RegisterCompression(name, func(level int) (CompressionCodec, error) {
+		if level == 42 {
+			return nil, errors.New("no")
+		}
+		return &xorCodec{key: 0x5a}, nil
+	})
+
+	if _, err := NewFileWriter([]byte(`"int"`), name, WithCompressionLevel(42)); err == nil {
+		t.Fatal("expected an error from the factory")
+	}
+
+	type record struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var buf bytes.Buffer
+	enc, err := NewEncoderFor[record](&buf, name, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []record{{Name: "jim", Age: 32}, {Name: "sheila", Age: 41}}
+	for i := range want {
+		if err := enc.Encode(&want[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(buf.Bytes(), []byte("sheila")) {
+		t.Fatal("data does not appear to be compressed")
+	}
+
+	var got []record
+	if err := ReadFileFor(&buf, func(val *record, rb *ResourceBank) error {
+		got = append(got, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
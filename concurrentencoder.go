@@ -0,0 +1,302 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// ConcurrentEncoder writes records of type T to an AVRO file, like Encoder. It
+// differs from Encoder in two ways: Encode may be called from many goroutines
+// at once, and blocks are compressed on a pool of worker goroutines. Blocks are
+// still written to the underlying writer one at a time, in the order they were
+// completed. Records from a single goroutine are written in the order that
+// goroutine encoded them.
+//
+// Blocks are written in the background, so errors writing blocks may be
+// returned by a later call to Encode, Flush or Close. You must call Close once
+// you have finished encoding records.
+type ConcurrentEncoder[T any] struct {
+	schema Schema
+	codec  Codec
+	fw     *FileWriter
+	w      io.Writer
+
+	approxBlockSize int
+	maxBlockRows    int
+	closeWriter     bool
+
+	// mu protects the fields used by Encode
+	mu     sync.Mutex
+	wb     *WriteBuf
+	count  int
+	last   *encodeJob
+	closed bool
+
+	jobs    chan *encodeJob
+	order   chan *encodeJob
+	buffers sync.Pool
+	wg      sync.WaitGroup
+
+	// wmu protects the fields updated as blocks are written
+	wmu   sync.Mutex
+	err   error
+	stats EncoderStats
+}
+
+// encodeJob is a block of records for a worker to compress
+type encodeJob struct {
+	count      int
+	data       *WriteBuf
+	compressed []byte
+	err        error
+	// compressedDone is closed when the block has been compressed
+	compressedDone chan struct{}
+	// written is closed when the block has been written
+	written chan struct{}
+}
+
+// WithCompressionWorkers sets the number of goroutines a ConcurrentEncoder uses
+// to compress blocks. The default is runtime.GOMAXPROCS(0). It is ignored by
+// other writers.
+func WithCompressionWorkers(workers int) WriteOption {
+	return func(wc *writeConfig) {
+		wc.workers = workers
+	}
+}
+
+// NewConcurrentEncoder returns a new ConcurrentEncoder. The parameters are as
+// for NewEncoderFor. WithMaxBlockRows, WithCloseWriter and
+// WithCompressionWorkers are also supported.
+func NewConcurrentEncoder[T any](w io.Writer, compression Compression, approxBlockSize int, opts ...WriteOption) (*ConcurrentEncoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	s, err := schemaForType(typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating schema: %w", err)
+	}
+
+	c, err := buildWriteCodec(s, typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+
+	schemaBytes, err := s.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling schema: %w", err)
+	}
+
+	fw, err := NewFileWriter(schemaBytes, compression, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating file writer: %w", err)
+	}
+
+	wc := newWriteConfig(opts)
+	workers := wc.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Each worker has its own compression codec
+	compressors := make([]CompressionCodec, workers)
+	for i := range compressors {
+		if compressors[i], err = newCompressionCodec(compression, wc.level); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fw.WriteHeader(w); err != nil {
+		return nil, fmt.Errorf("writing file header: %w", err)
+	}
+
+	e := &ConcurrentEncoder[T]{
+		schema: s,
+		codec:  c,
+		fw:     fw,
+		w:      w,
+
+		approxBlockSize: approxBlockSize,
+		maxBlockRows:    wc.maxBlockRows,
+		closeWriter:     wc.closeWriter,
+
+		jobs:  make(chan *encodeJob, workers),
+		order: make(chan *encodeJob, 2*workers),
+	}
+	e.buffers.New = func() any {
+		return NewWriteBuf(make([]byte, 0, approxBlockSize))
+	}
+	e.wb = e.buffers.Get().(*WriteBuf)
+
+	e.wg.Add(workers + 1)
+	for _, compressor := range compressors {
+		go e.compress(compressor)
+	}
+	go e.write()
+
+	return e, nil
+}
+
+// compress compresses blocks from the jobs channel until it is closed.
+func (e *ConcurrentEncoder[T]) compress(compressor CompressionCodec) {
+	defer e.wg.Done()
+	for job := range e.jobs {
+		compressed, err := compressor.Compress(job.data.Bytes())
+		if err != nil {
+			job.err = fmt.Errorf("compressing block: %w", err)
+		} else {
+			// The compressor may re-use its output buffer
+			job.compressed = append(job.compressed, compressed...)
+		}
+		close(job.compressedDone)
+	}
+}
+
+// write writes blocks to the underlying writer in the order they were
+// completed. Once there's an error no further blocks are written.
+func (e *ConcurrentEncoder[T]) write() {
+	defer e.wg.Done()
+	for job := range e.order {
+		<-job.compressedDone
+
+		e.wmu.Lock()
+		err := e.err
+		if err == nil {
+			err = job.err
+		}
+		if err == nil {
+			err = e.fw.writeCompressedBlock(e.w, job.count, job.compressed)
+		}
+		if err != nil {
+			e.err = err
+		} else {
+			e.stats.Rows += int64(job.count)
+			e.stats.Blocks++
+			e.stats.UncompressedBytes += int64(job.data.Len())
+			e.stats.CompressedBytes += int64(len(job.compressed))
+		}
+		e.wmu.Unlock()
+
+		job.data.Reset()
+		e.buffers.Put(job.data)
+		close(job.written)
+	}
+}
+
+// writeErr returns the first error that occurred writing blocks.
+func (e *ConcurrentEncoder[T]) writeErr() error {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	return e.err
+}
+
+// Encode adds a record to the file. It is safe to call Encode from multiple
+// goroutines. If the row cannot be encoded an error is returned and the row is
+// discarded.
+func (e *ConcurrentEncoder[T]) Encode(v *T) error {
+	if err := e.writeErr(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errEncoderClosed
+	}
+
+	start := e.wb.Len()
+	e.codec.Write(e.wb, unsafe.Pointer(v))
+	if err := e.wb.Err(); err != nil {
+		e.wb.truncate(start)
+		return fmt.Errorf("encoding row: %w", err)
+	}
+	e.count++
+
+	if e.wb.Len() >= e.approxBlockSize || (e.maxBlockRows > 0 && e.count >= e.maxBlockRows) {
+		e.submit()
+	}
+	return nil
+}
+
+// submit passes the current block to be compressed and written. It must be
+// called with e.mu held.
+func (e *ConcurrentEncoder[T]) submit() {
+	if e.count == 0 {
+		return
+	}
+	job := &encodeJob{
+		count:          e.count,
+		data:           e.wb,
+		compressedDone: make(chan struct{}),
+		written:        make(chan struct{}),
+	}
+	// The order channel defines the order blocks are written. We add the job
+	// to it before passing it to a worker so that the writer never waits for
+	// a block that hasn't been queued.
+	e.order <- job
+	e.jobs <- job
+	e.last = job
+
+	e.wb = e.buffers.Get().(*WriteBuf)
+	e.count = 0
+}
+
+// Flush completes the current block and waits until it and all the blocks
+// before it have been written to the underlying writer.
+func (e *ConcurrentEncoder[T]) Flush() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return errEncoderClosed
+	}
+	e.submit()
+	last := e.last
+	e.mu.Unlock()
+
+	if last != nil {
+		<-last.written
+	}
+	return e.writeErr()
+}
+
+// Close writes any buffered records and waits for all blocks to be written. It
+// then stops the worker goroutines. If WithCloseWriter was passed when the
+// ConcurrentEncoder was created it then closes the underlying writer, if it
+// implements io.Closer, even if writing the last blocks failed. The
+// ConcurrentEncoder can't be used after Close is called. Calling Close again
+// does nothing.
+func (e *ConcurrentEncoder[T]) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.submit()
+	close(e.jobs)
+	close(e.order)
+	e.mu.Unlock()
+
+	e.wg.Wait()
+
+	err := e.writeErr()
+	if c, ok := e.w.(io.Closer); ok && e.closeWriter {
+		if cerr := c.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("closing writer: %w", cerr))
+		}
+	}
+	return err
+}
+
+// Stats returns counts of the data written so far.
+func (e *ConcurrentEncoder[T]) Stats() EncoderStats {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	return e.stats
+}
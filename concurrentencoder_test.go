@@ -0,0 +1,160 @@
+package avro_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+type concurrentRecord struct {
+	Worker int    `json:"worker"`
+	Seq    int    `json:"seq"`
+	Name   string `json:"name"`
+}
+
+func readConcurrentFile(t *testing.T, data []byte) []concurrentRecord {
+	t.Helper()
+	var actual []concurrentRecord
+	if err := avro.ReadFileFor(bufio.NewReader(bytes.NewReader(data)), func(val *concurrentRecord, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return actual
+}
+
+func TestConcurrentEncoderOrder(t *testing.T) {
+	for _, compression := range []avro.Compression{avro.CompressionNull, avro.CompressionDeflate, avro.CompressionSnappy} {
+		t.Run(string(compression), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := avro.NewConcurrentEncoder[concurrentRecord](&buf, compression, 100, avro.WithCompressionWorkers(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var contents []concurrentRecord
+			for i := range 1000 {
+				contents = append(contents, concurrentRecord{Seq: i, Name: fmt.Sprintf("record %d", i)})
+				if err := enc.Encode(&contents[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(contents, readConcurrentFile(t, buf.Bytes())); diff != "" {
+				t.Fatalf("records not as expected. %s", diff)
+			}
+
+			stats := enc.Stats()
+			if stats.Rows != 1000 || stats.Blocks < 2 {
+				t.Fatalf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestConcurrentEncoderGoroutines(t *testing.T) {
+	const (
+		workers = 8
+		count   = 500
+	)
+
+	var buf bytes.Buffer
+	enc, err := avro.NewConcurrentEncoder[concurrentRecord](&buf, avro.CompressionDeflate, 1000, avro.WithMaxBlockRows(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			for i := range count {
+				if err := enc.Encode(&concurrentRecord{Worker: w, Seq: i, Name: "hat"}); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%100 == 0 {
+					if err := enc.Flush(); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records from each goroutine should be in order
+	next := make([]int, workers)
+	for _, r := range readConcurrentFile(t, buf.Bytes()) {
+		if r.Seq != next[r.Worker] {
+			t.Fatalf("worker %d: got record %d, expected %d", r.Worker, r.Seq, next[r.Worker])
+		}
+		next[r.Worker]++
+	}
+	for w, n := range next {
+		if n != count {
+			t.Errorf("worker %d: got %d records, expected %d", w, n, count)
+		}
+	}
+	if stats := enc.Stats(); stats.Rows != workers*count {
+		t.Errorf("stats show %d rows", stats.Rows)
+	}
+}
+
+type failingWriter struct {
+	n      int
+	closed bool
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("disk full")
+	}
+	f.n--
+	return len(p), nil
+}
+
+func (f *failingWriter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestConcurrentEncoderWriteError(t *testing.T) {
+	// Allow the header to be written
+	w := &failingWriter{n: 1}
+	enc, err := avro.NewConcurrentEncoder[concurrentRecord](w, avro.CompressionNull, 10, avro.WithCloseWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&concurrentRecord{Name: "a long enough name to fill a block"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err == nil {
+		t.Fatal("expected an error from Flush")
+	}
+	if err := enc.Encode(&concurrentRecord{}); err == nil {
+		t.Fatal("expected an error from Encode")
+	}
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected an error from Close")
+	}
+	if !w.closed {
+		t.Error("writer not closed")
+	}
+	if err := enc.Encode(&concurrentRecord{}); err == nil {
+		t.Fatal("expected an error after Close")
+	}
+}
@@ -0,0 +1,171 @@
+// Package confluent encodes and decodes AVRO records framed in the Confluent
+// schema registry wire format, as used for messages on Kafka topics. Each
+// message is a zero magic byte, followed by the 4 byte big-endian ID of the
+// writer's schema in the schema registry, followed by the AVRO binary encoding
+// of the record.
+package confluent
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/philpearl/avro"
+)
+
+// MagicByte is the first byte of a message in the Confluent wire format.
+const MagicByte = 0
+
+// headerLen is the length of the magic byte and schema ID
+const headerLen = 5
+
+// Encoder encodes values of type T in the Confluent wire format. It is safe for
+// concurrent use.
+type Encoder[T any] struct {
+	writer *avro.DatumWriter[T]
+	header [headerLen]byte
+}
+
+// NewEncoder returns a new Encoder. The schema is derived from T using
+// avro.SchemaForType, and is registered under subject in registry to find its
+// ID.
+func NewEncoder[T any](ctx context.Context, registry SchemaRegistry, subject string) (*Encoder[T], error) {
+	var t T
+
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	s, err := avro.SchemaForType(t)
+	if err != nil {
+		return nil, fmt.Errorf("generating schema: %w", err)
+	}
+
+	writer, err := avro.NewDatumWriter[T](s)
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+
+	id, err := registry.Register(ctx, subject, s)
+	if err != nil {
+		return nil, fmt.Errorf("registering schema: %w", err)
+	}
+
+	e := &Encoder[T]{writer: writer}
+	e.header[0] = MagicByte
+	binary.BigEndian.PutUint32(e.header[1:], id)
+	return e, nil
+}
+
+// Schema returns the schema values are encoded with.
+func (e *Encoder[T]) Schema() avro.Schema {
+	return e.writer.Schema()
+}
+
+// SchemaID returns the registry ID of the schema values are encoded with.
+func (e *Encoder[T]) SchemaID() uint32 {
+	return binary.BigEndian.Uint32(e.header[1:])
+}
+
+// Encode returns the encoding of v in the Confluent wire format.
+func (e *Encoder[T]) Encode(v *T) ([]byte, error) {
+	return e.AppendEncode(nil, v)
+}
+
+// AppendEncode appends the encoding of v to buf. If v cannot be encoded an
+// error is returned along with buf unchanged.
+func (e *Encoder[T]) AppendEncode(buf []byte, v *T) ([]byte, error) {
+	start := len(buf)
+	out, err := e.writer.Append(append(buf, e.header[:]...), v)
+	if err != nil {
+		return buf[:start], err
+	}
+	return out, nil
+}
+
+// Decoder decodes values in the Confluent wire format into values of type T. It
+// finds the schema the data was written with in a SchemaRegistry and resolves
+// it against the schema for T using AVRO schema resolution. It is safe for
+// concurrent use.
+type Decoder[T any] struct {
+	registry SchemaRegistry
+	schema   avro.Schema
+
+	mu      sync.RWMutex
+	readers map[uint32]*avro.DatumReader[T]
+}
+
+// NewDecoder returns a new Decoder that looks up writer schemas in registry.
+// The reader's schema is derived from T using avro.SchemaForType. Decoder keeps
+// the codec for each schema ID, but does not otherwise cache schemas, so
+// consider wrapping registry with NewCachingRegistry.
+func NewDecoder[T any](registry SchemaRegistry) (*Decoder[T], error) {
+	var t T
+	reader, err := avro.SchemaForType(t)
+	if err != nil {
+		return nil, fmt.Errorf("building reader schema: %w", err)
+	}
+	return &Decoder[T]{
+		registry: registry,
+		schema:   reader,
+		readers:  make(map[uint32]*avro.DatumReader[T]),
+	}, nil
+}
+
+// Decode decodes data into v. Strings and byte slices in v do not share memory
+// with data.
+func (d *Decoder[T]) Decode(ctx context.Context, data []byte, v *T) error {
+	id, body, err := split(data)
+	if err != nil {
+		return err
+	}
+
+	reader, err := d.reader(ctx, id)
+	if err != nil {
+		return err
+	}
+	return reader.Read(body, v)
+}
+
+func (d *Decoder[T]) reader(ctx context.Context, id uint32) (*avro.DatumReader[T], error) {
+	d.mu.RLock()
+	r, ok := d.readers[id]
+	d.mu.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	writer, err := d.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("finding writer schema: %w", err)
+	}
+
+	if r, err = avro.NewDatumReader[T](writer, avro.WithReaderSchema(d.schema)); err != nil {
+		return nil, fmt.Errorf("building codec for schema ID %d: %w", id, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readers[id] = r
+	return r, nil
+}
+
+// SchemaID returns the ID of the writer's schema from data in the Confluent
+// wire format.
+func SchemaID(data []byte) (uint32, error) {
+	id, _, err := split(data)
+	return id, err
+}
+
+func split(data []byte) (id uint32, body []byte, err error) {
+	if len(data) < headerLen {
+		return 0, nil, fmt.Errorf("data too short for Confluent wire format: %d bytes", len(data))
+	}
+	if data[0] != MagicByte {
+		return 0, nil, fmt.Errorf("unexpected magic byte %d", data[0])
+	}
+	return binary.BigEndian.Uint32(data[1:]), data[headerLen:], nil
+}
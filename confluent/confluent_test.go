@@ -0,0 +1,128 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	type event struct {
+		ID    int64   `json:"id"`
+		Kind  string  `json:"kind" avro:"enum=CREATE|DELETE"`
+		Score float64 `json:"score"`
+	}
+
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+
+	// Register something else first so the schema doesn't get ID 1
+	type other struct {
+		Name string `json:"name"`
+	}
+	if _, err := NewEncoder[other](ctx, registry, "other-value"); err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := NewEncoder[event](ctx, registry, "events-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.SchemaID() != 2 {
+		t.Fatalf("schema ID is %d, want 2", enc.SchemaID())
+	}
+
+	in := event{ID: 1, Kind: "DELETE", Score: 0.5}
+	data, err := enc.Encode(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{0, 0, 0, 0, 2}, data[:5]); diff != "" {
+		t.Fatalf("header not as expected (-want +got):\n%s", diff)
+	}
+	if id, err := SchemaID(data); err != nil || id != 2 {
+		t.Fatalf("SchemaID returned %d, %v", id, err)
+	}
+
+	appended, err := enc.AppendEncode([]byte{9}, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(append([]byte{9}, data...), appended); diff != "" {
+		t.Fatalf("appended data not as expected (-want +got):\n%s", diff)
+	}
+
+	dec, err := NewDecoder[event](NewCachingRegistry(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out event
+	if err := dec.Decode(ctx, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out); diff != "" {
+		t.Fatalf("decoded value not as expected (-want +got):\n%s", diff)
+	}
+
+	// A type with fewer fields can read the data using schema resolution
+	type smaller struct {
+		ID int64 `json:"id"`
+	}
+	smallDec, err := NewDecoder[smaller](registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var small smaller
+	if err := smallDec.Decode(ctx, data, &small); err != nil {
+		t.Fatal(err)
+	}
+	if small.ID != 1 {
+		t.Fatalf("decoded ID %d, want 1", small.ID)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	type event struct {
+		ID int64 `json:"id"`
+	}
+
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	dec, err := NewDecoder[event](registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out event
+	if err := dec.Decode(ctx, []byte{0, 0, 0, 0, 1, 2}, &out); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	enc, err := NewEncoder[event](ctx, registry, "events-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := enc.Encode(&event{ID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "short", data: data[:4]},
+		{name: "bad magic", data: append([]byte{1}, data[1:]...)},
+		{name: "truncated body", data: data[:5]},
+		{name: "trailing data", data: append(data[:len(data):len(data)], 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := dec.Decode(ctx, test.data, &out); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
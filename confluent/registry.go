@@ -0,0 +1,163 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/philpearl/avro"
+)
+
+// ErrNotFound is returned by a SchemaRegistry if it has no schema with the
+// requested ID.
+var ErrNotFound = errors.New("schema not found")
+
+// SchemaRegistry maps between schemas and the IDs a schema registry assigns
+// them. A client for a Confluent schema registry would implement this
+// interface.
+type SchemaRegistry interface {
+	// SchemaByID returns the schema with the given ID. It should return an
+	// error wrapping ErrNotFound if there is no such schema.
+	SchemaByID(ctx context.Context, id uint32) (avro.Schema, error)
+	// Register registers the schema under the given subject and returns its
+	// ID. If the schema is already registered it returns the existing ID.
+	Register(ctx context.Context, subject string, schema avro.Schema) (uint32, error)
+}
+
+// CachingRegistry wraps a SchemaRegistry, caching the results of lookups so
+// each schema and ID is requested at most once. It is safe for concurrent use
+// if the underlying registry is.
+type CachingRegistry struct {
+	registry SchemaRegistry
+
+	mu      sync.RWMutex
+	schemas map[uint32]avro.Schema
+	ids     map[subjectSchema]uint32
+}
+
+// subjectSchema identifies a schema registered under a subject. Schemas are
+// identified by their fingerprint.
+type subjectSchema struct {
+	subject     string
+	fingerprint uint64
+}
+
+// NewCachingRegistry returns a CachingRegistry that caches responses from
+// registry.
+func NewCachingRegistry(registry SchemaRegistry) *CachingRegistry {
+	return &CachingRegistry{
+		registry: registry,
+		schemas:  make(map[uint32]avro.Schema),
+		ids:      make(map[subjectSchema]uint32),
+	}
+}
+
+// SchemaByID returns the schema with the given ID.
+func (c *CachingRegistry) SchemaByID(ctx context.Context, id uint32) (avro.Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := c.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return avro.Schema{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemas[id] = schema
+	return schema, nil
+}
+
+// Register registers the schema under the given subject and returns its ID.
+func (c *CachingRegistry) Register(ctx context.Context, subject string, schema avro.Schema) (uint32, error) {
+	fingerprint, err := schema.Fingerprint()
+	if err != nil {
+		return 0, fmt.Errorf("fingerprinting schema: %w", err)
+	}
+	key := subjectSchema{subject: subject, fingerprint: fingerprint}
+
+	c.mu.RLock()
+	id, ok := c.ids[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err = c.registry.Register(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[key] = id
+	c.schemas[id] = schema
+	return id, nil
+}
+
+// MemoryRegistry is a SchemaRegistry that keeps schemas in memory. It's
+// intended for tests and other cases where a real schema registry isn't
+// available. Like a Confluent registry it gives a schema the same ID whichever
+// subject it is registered under. IDs start at 1. It is safe for concurrent
+// use.
+type MemoryRegistry struct {
+	mu       sync.Mutex
+	schemas  []avro.Schema
+	ids      map[uint64]uint32
+	subjects map[string][]uint32
+}
+
+// NewMemoryRegistry returns an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		ids:      make(map[uint64]uint32),
+		subjects: make(map[string][]uint32),
+	}
+}
+
+// SchemaByID returns the schema with the given ID.
+func (m *MemoryRegistry) SchemaByID(ctx context.Context, id uint32) (avro.Schema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == 0 || int(id) > len(m.schemas) {
+		return avro.Schema{}, fmt.Errorf("schema ID %d: %w", id, ErrNotFound)
+	}
+	return m.schemas[id-1], nil
+}
+
+// Register registers the schema under the given subject and returns its ID.
+func (m *MemoryRegistry) Register(ctx context.Context, subject string, schema avro.Schema) (uint32, error) {
+	fingerprint, err := schema.Fingerprint()
+	if err != nil {
+		return 0, fmt.Errorf("fingerprinting schema: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.ids[fingerprint]
+	if !ok {
+		m.schemas = append(m.schemas, schema)
+		id = uint32(len(m.schemas))
+		m.ids[fingerprint] = id
+	}
+	for _, existing := range m.subjects[subject] {
+		if existing == id {
+			return id, nil
+		}
+	}
+	m.subjects[subject] = append(m.subjects[subject], id)
+	return id, nil
+}
+
+// Versions returns the IDs of the schemas registered under the subject, in the
+// order they were registered.
+func (m *MemoryRegistry) Versions(subject string) []uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]uint32(nil), m.subjects[subject]...)
+}
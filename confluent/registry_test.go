@@ -0,0 +1,114 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+func TestMemoryRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRegistry()
+
+	a := avro.Schema{Type: "string"}
+	b := avro.Schema{Type: "long"}
+	// Same canonical form as b
+	b2 := avro.Schema{Type: "long", Object: &avro.SchemaObject{LogicalType: "timestamp-micros"}}
+
+	ids := make([]uint32, 0, 4)
+	for _, reg := range []struct {
+		subject string
+		schema  avro.Schema
+	}{
+		{"one", a},
+		{"one", b},
+		{"two", b2},
+		{"one", a},
+	} {
+		id, err := r.Register(ctx, reg.subject, reg.schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if diff := cmp.Diff([]uint32{1, 2, 2, 1}, ids); diff != "" {
+		t.Fatalf("IDs not as expected (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]uint32{1, 2}, r.Versions("one")); diff != "" {
+		t.Fatalf("versions not as expected (-want +got):\n%s", diff)
+	}
+
+	got, err := r.SchemaByID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(b, got); diff != "" {
+		t.Fatalf("schema not as expected (-want +got):\n%s", diff)
+	}
+
+	for _, id := range []uint32{0, 3} {
+		if _, err := r.SchemaByID(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ID %d: expected not found error, got %v", id, err)
+		}
+	}
+}
+
+type countingRegistry struct {
+	SchemaRegistry
+	lookups   int
+	registers int
+}
+
+func (c *countingRegistry) SchemaByID(ctx context.Context, id uint32) (avro.Schema, error) {
+	c.lookups++
+	return c.SchemaRegistry.SchemaByID(ctx, id)
+}
+
+func (c *countingRegistry) Register(ctx context.Context, subject string, schema avro.Schema) (uint32, error) {
+	c.registers++
+	return c.SchemaRegistry.Register(ctx, subject, schema)
+}
+
+func TestCachingRegistry(t *testing.T) {
+	ctx := context.Background()
+	counter := &countingRegistry{SchemaRegistry: NewMemoryRegistry()}
+	r := NewCachingRegistry(counter)
+
+	s := avro.Schema{Type: "string"}
+	for range 3 {
+		id, err := r.Register(ctx, "subject", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != 1 {
+			t.Fatalf("ID is %d, want 1", id)
+		}
+	}
+	if _, err := r.Register(ctx, "other", s); err != nil {
+		t.Fatal(err)
+	}
+	if counter.registers != 2 {
+		t.Fatalf("expected 2 registrations, got %d", counter.registers)
+	}
+
+	// Registering caches the schema for its ID
+	if _, err := r.SchemaByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if counter.lookups != 0 {
+		t.Fatalf("expected no lookups, got %d", counter.lookups)
+	}
+
+	// Errors are not cached
+	for range 2 {
+		if _, err := r.SchemaByID(ctx, 2); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	}
+	if counter.lookups != 2 {
+		t.Fatalf("expected 2 lookups, got %d", counter.lookups)
+	}
+}
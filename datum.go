@@ -0,0 +1,154 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Marshal returns the AVRO binary encoding of v using the given schema. Only
+// the value itself is encoded: there's no header or schema information, so
+// the same schema is needed to decode the data. Each call marshals the schema
+// to JSON to find the codec in a cache, so Marshal is for occasional use. Use a
+// DatumWriter when encoding many values.
+func Marshal[T any](schema Schema, v *T) ([]byte, error) {
+	c, err := cachedDatumCodec(schema, reflect.TypeFor[T](), true)
+	if err != nil {
+		return nil, err
+	}
+	return appendDatum(nil, c, unsafe.Pointer(v))
+}
+
+// Unmarshal decodes data, the AVRO binary encoding of a single value with the
+// given schema, into v. Strings and byte slices in v do not share memory with
+// data. As with Marshal each call looks up the codec by the schema JSON, so use
+// a DatumReader when decoding many values.
+func Unmarshal[T any](schema Schema, data []byte, v *T) error {
+	c, err := cachedDatumCodec(schema, reflect.TypeFor[T](), false)
+	if err != nil {
+		return err
+	}
+	return readDatum(c, data, v)
+}
+
+type datumCodecKey struct {
+	schema  string
+	typ     reflect.Type
+	writing bool
+}
+
+// datumCodecs caches the codecs used by Marshal and Unmarshal. The key is the
+// schema JSON rather than its fingerprint, as attributes that are not in the
+// canonical form, such as aliases, can change how Go types are mapped onto the
+// schema. Entries are never removed, so the cache holds a codec for every
+// schema and type Marshal and Unmarshal have been called with.
+var datumCodecs sync.Map
+
+func cachedDatumCodec(schema Schema, typ reflect.Type, writing bool) (Codec, error) {
+	schemaJSON, err := schema.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling schema: %w", err)
+	}
+	key := datumCodecKey{schema: string(schemaJSON), typ: typ, writing: writing}
+	if c, ok := datumCodecs.Load(key); ok {
+		return c.(Codec), nil
+	}
+
+	var c Codec
+	if writing {
+		c, err = buildWriteCodec(schema, typ)
+	} else {
+		c, err = buildCodec(schema, typ, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("building codec: %w", err)
+	}
+	datumCodecs.Store(key, c)
+	return c, nil
+}
+
+func appendDatum(buf []byte, c Codec, p unsafe.Pointer) ([]byte, error) {
+	start := len(buf)
+	w := NewWriteBuf(buf)
+	c.Write(w, p)
+	if err := w.Err(); err != nil {
+		return buf[:start], fmt.Errorf("encoding value: %w", err)
+	}
+	return w.Bytes(), nil
+}
+
+func readDatum[T any](c Codec, data []byte, v *T) error {
+	var zero T
+	*v = zero
+	r := NewReadBuf(data)
+	if err := c.Read(r, unsafe.Pointer(v)); err != nil {
+		return fmt.Errorf("decoding value: %w", err)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d unexpected bytes after value", r.Len())
+	}
+	return nil
+}
+
+// DatumWriter encodes values of type T using a fixed schema. Only the value
+// itself is encoded, as with Marshal. It is safe for concurrent use.
+type DatumWriter[T any] struct {
+	schema Schema
+	codec  Codec
+}
+
+// NewDatumWriter returns a DatumWriter that encodes values of type T with the
+// given schema. Use SchemaForType to find a schema for T.
+func NewDatumWriter[T any](schema Schema) (*DatumWriter[T], error) {
+	c, err := buildWriteCodec(schema, reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("building codec: %w", err)
+	}
+	return &DatumWriter[T]{schema: schema, codec: c}, nil
+}
+
+// Schema returns the schema values are encoded with.
+func (d *DatumWriter[T]) Schema() Schema {
+	return d.schema
+}
+
+// Write returns the AVRO binary encoding of v.
+func (d *DatumWriter[T]) Write(v *T) ([]byte, error) {
+	return appendDatum(nil, d.codec, unsafe.Pointer(v))
+}
+
+// Append appends the AVRO binary encoding of v to buf. If v cannot be encoded
+// an error is returned along with buf unchanged.
+func (d *DatumWriter[T]) Append(buf []byte, v *T) ([]byte, error) {
+	return appendDatum(buf, d.codec, unsafe.Pointer(v))
+}
+
+// DatumReader decodes values of type T from data encoded with a fixed schema,
+// as produced by Marshal or DatumWriter. It is safe for concurrent use.
+type DatumReader[T any] struct {
+	codec Codec
+}
+
+// NewDatumReader returns a DatumReader that decodes data written with the
+// given schema into values of type T. Pass WithReaderSchema or
+// WithSchemaResolution to use AVRO schema resolution if the schema does not
+// match T.
+func NewDatumReader[T any](schema Schema, opts ...ReadOption) (*DatumReader[T], error) {
+	var rc readConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+
+	c, err := rc.codec(schema, reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("building codec: %w", err)
+	}
+	return &DatumReader[T]{codec: c}, nil
+}
+
+// Read decodes data into v. Strings and byte slices in v do not share memory
+// with data.
+func (d *DatumReader[T]) Read(data []byte, v *T) error {
+	return readDatum(d.codec, data, v)
+}
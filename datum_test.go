@@ -0,0 +1,124 @@
+package avro_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/philpearl/avro"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	type thing struct {
+		Name  string           `json:"name"`
+		Count int64            `json:"count"`
+		Attrs map[string]int32 `json:"attrs"`
+		Next  *thing           `json:"next"`
+	}
+
+	schema, err := avro.SchemaForType(thing{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := thing{Name: "a", Count: 1, Attrs: map[string]int32{"x": 2}, Next: &thing{Name: "b"}}
+	data, err := avro.Marshal(schema, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out thing
+	if err := avro.Unmarshal(schema, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("result not as expected (-want +got):\n%s", diff)
+	}
+
+	// The writer and reader give the same results
+	w, err := avro.NewDatumWriter[thing](schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := w.Append([]byte{1, 2}, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(append([]byte{1, 2}, data...), data2); diff != "" {
+		t.Fatalf("data not as expected (-want +got):\n%s", diff)
+	}
+
+	r, err := avro.NewDatumReader[thing](schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = thing{Count: 7}
+	if err := r.Read(data2[2:], &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("result not as expected (-want +got):\n%s", diff)
+	}
+
+	if err := r.Read(data[:len(data)-1], &out); err == nil {
+		t.Fatal("expected an error for truncated data")
+	}
+	if err := r.Read(append(data, 0), &out); err == nil {
+		t.Fatal("expected an error for trailing data")
+	}
+}
+
+func TestMarshalPrimitive(t *testing.T) {
+	v := int64(-3)
+	data, err := avro.Marshal(avro.Schema{Type: "long"}, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{5}, data); diff != "" {
+		t.Fatalf("data not as expected (-want +got):\n%s", diff)
+	}
+
+	var s string
+	if err := avro.Unmarshal(avro.Schema{Type: "string"}, []byte{6, 'h', 'a', 't'}, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "hat" {
+		t.Fatalf("got %q, want hat", s)
+	}
+
+	if _, err := avro.Marshal(avro.Schema{Type: "string"}, &v); err == nil {
+		t.Fatal("expected an error for mismatched type")
+	}
+}
+
+func TestDatumReaderResolution(t *testing.T) {
+	type v1 struct {
+		ID   int32  `json:"id"`
+		Name string `json:"name"`
+	}
+	type v2 struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email" avro:"default=unknown"`
+	}
+
+	schema, err := avro.SchemaFromString(`{"type":"record","name":"v1","fields":[{"name":"id","type":"int"},{"name":"name","type":"string"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := avro.Marshal(schema, &v1{ID: 3, Name: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := avro.NewDatumReader[v2](schema, avro.WithSchemaResolution())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out v2
+	if err := r.Read(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(v2{ID: 3, Email: "unknown"}, out); diff != "" {
+		t.Fatalf("result not as expected (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,249 @@
+package avro
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// defaultValue converts the JSON default value for a field with the given
+// schema into the natural Go type for the schema (see naturalType). Defaults
+// for unions match the first branch of the union that accepts the value.
+func defaultValue(schema Schema, data jsontext.Value) (any, error) {
+	switch schema.Type {
+	case "null":
+		if data.Kind() != 'n' {
+			return nil, fmt.Errorf("default for null must be null, not %s", data)
+		}
+		return nil, nil
+	case "boolean":
+		return unmarshalDefault[bool](data)
+	case "int":
+		return unmarshalDefault[int32](data)
+	case "long":
+		return unmarshalDefault[int64](data)
+	case "float":
+		return unmarshalDefault[float32](data)
+	case "double":
+		return unmarshalDefault[float64](data)
+	case "string":
+		return unmarshalDefault[string](data)
+	case "bytes":
+		return bytesDefault(data)
+	case "fixed":
+		b, err := bytesDefault(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != schema.Object.Size {
+			return nil, fmt.Errorf("default for fixed of size %d has %d bytes", schema.Object.Size, len(b))
+		}
+		v := reflect.New(reflect.ArrayOf(len(b), reflect.TypeFor[byte]())).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case "enum":
+		s, err := unmarshalDefault[string](data)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range schema.Object.Symbols {
+			if sym == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("default %q is not a symbol of enum %s", s, schema.Object.Name)
+	case "array":
+		items, err := unmarshalDefault[[]jsontext.Value](data)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			if values[i], err = defaultValue(schema.Object.Items, item); err != nil {
+				return nil, fmt.Errorf("array item %d: %w", i, err)
+			}
+		}
+		return values, nil
+	case "map":
+		entries, err := unmarshalDefault[map[string]jsontext.Value](data)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]any, len(entries))
+		for key, entry := range entries {
+			if values[key], err = defaultValue(schema.Object.Values, entry); err != nil {
+				return nil, fmt.Errorf("map entry %q: %w", key, err)
+			}
+		}
+		return values, nil
+	case "record":
+		entries, err := unmarshalDefault[map[string]jsontext.Value](data)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]any, len(schema.Object.Fields))
+		for _, f := range schema.Object.Fields {
+			entry, ok := entries[f.Name]
+			if !ok {
+				// Fields missing from a record default take the field's own
+				// default.
+				if entry = f.Default; len(entry) == 0 {
+					return nil, fmt.Errorf("default for record %s has no value for field %q", schema.Object.Name, f.Name)
+				}
+			}
+			if values[f.Name], err = defaultValue(f.Type, entry); err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+		}
+		return values, nil
+	case "union":
+		_, v, err := unionDefault(schema, data)
+		return v, err
+	}
+	return nil, fmt.Errorf("defaults not supported for %s", schema.Type)
+}
+
+// unionDefault finds the branch of a union that the default value data
+// belongs to. The AVRO spec says this should be the first branch, but we
+// accept any branch that matches.
+func unionDefault(schema Schema, data jsontext.Value) (int, any, error) {
+	for i, branch := range schema.Union {
+		if v, err := defaultValue(branch, data); err == nil {
+			return i, v, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("default %s does not match any branch of union", data)
+}
+
+func unmarshalDefault[T any](data jsontext.Value) (T, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("invalid default %s for %T: %w", data, v, err)
+	}
+	return v, nil
+}
+
+// bytesDefault decodes a default for bytes or fixed. These are JSON strings
+// where each code point from 0 to 255 is one byte.
+func bytesDefault(data jsontext.Value) ([]byte, error) {
+	s, err := unmarshalDefault[string](data)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > math.MaxUint8 {
+			return nil, fmt.Errorf("default %s for bytes contains code point %U, which is out of range", data, r)
+		}
+		b = append(b, byte(r))
+	}
+	return b, nil
+}
+
+// encodeDefault converts the JSON default value for a field with the given
+// schema into AVRO binary.
+func encodeDefault(schema Schema, data jsontext.Value) ([]byte, error) {
+	var w WriteBuf
+	if schema.Type == "union" {
+		index, v, err := unionDefault(schema, data)
+		if err != nil {
+			return nil, err
+		}
+		w.Varint(int64(index))
+		if err := writeDefault(&w, schema.Union[index], v); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	}
+
+	v, err := defaultValue(schema, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDefault(&w, schema, v); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func writeDefault(w *WriteBuf, schema Schema, v any) error {
+	c, err := buildCodec(schema, anyType, false)
+	if err != nil {
+		return fmt.Errorf("building codec for default: %w", err)
+	}
+	c.Write(w, unsafe.Pointer(&v))
+	return w.Err()
+}
+
+// defaultCodec reads a field from a pre-encoded default value rather than from
+// the data. It's used for fields that aren't in the writer's schema, so it
+// neither skips nor writes anything.
+type defaultCodec struct {
+	Codec
+	data []byte
+}
+
+func (b *codecBuilder) buildDefaultCodec(schema Schema, data jsontext.Value, typ reflect.Type) (Codec, error) {
+	encoded, err := encodeDefault(schema, data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.build(schema, typ, false)
+	if err != nil {
+		return nil, err
+	}
+	return &defaultCodec{Codec: c, data: encoded}, nil
+}
+
+func (c *defaultCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	d := ReadBuf{buf: c.data, rb: r.rb}
+	return c.Codec.Read(&d, p)
+}
+
+func (c *defaultCodec) Skip(r *ReadBuf) error {
+	return nil
+}
+
+func (c *defaultCodec) Omit(p unsafe.Pointer) bool {
+	return false
+}
+
+func (c *defaultCodec) Write(w *WriteBuf, p unsafe.Pointer) {}
+
+// defaultFromTag converts the default in an `avro:"default=..."` tag into JSON
+// for a field with the given schema. Defaults for strings, bytes, enums and
+// fixed are given literally. Everything else is JSON.
+func defaultFromTag(schema Schema, tag string) (jsontext.Value, error) {
+	base := schema
+	if schema.Type == "union" {
+		if tag == "null" {
+			return jsontext.Value("null"), nil
+		}
+		for _, branch := range schema.Union {
+			if branch.Type != "null" {
+				base = branch
+				break
+			}
+		}
+	}
+
+	var data jsontext.Value
+	switch base.Type {
+	case "string", "bytes", "enum", "fixed":
+		var err error
+		if data, err = json.Marshal(tag); err != nil {
+			return nil, err
+		}
+	default:
+		data = jsontext.Value(tag)
+	}
+
+	if _, err := defaultValue(schema, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
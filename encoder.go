@@ -1,9 +1,12 @@
 package avro
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 	"unsafe"
 )
 
@@ -14,17 +17,66 @@ type Encoder[T any] struct {
 	w      io.Writer
 
 	approxBlockSize int
-	wb              *WriteBuf
-	count           int
+	maxBlockRows    int
+	flushInterval   time.Duration
+	closeWriter     bool
+
+	wb    *WriteBuf
+	count int
+	// blockStart is when the first row of the current block was encoded
+	blockStart time.Time
+	closed     bool
+	stats      EncoderStats
+}
+
+// EncoderStats are counts of the data an Encoder has written. Rows are counted
+// when the block containing them is written, not when they are encoded.
+type EncoderStats struct {
+	// Rows is the number of rows written.
+	Rows int64
+	// Blocks is the number of blocks written.
+	Blocks int64
+	// UncompressedBytes is the size of the blocks written before compression.
+	UncompressedBytes int64
+	// CompressedBytes is the size of the blocks written after compression.
+	// It doesn't include the file header, or the row count, length and sync
+	// marker written with each block.
+	CompressedBytes int64
+}
+
+// WithMaxBlockRows causes an Encoder to write a block once it contains n rows,
+// even if it is smaller than approxBlockSize. It is ignored by FileWriter.
+func WithMaxBlockRows(n int) WriteOption {
+	return func(wc *writeConfig) {
+		wc.maxBlockRows = n
+	}
+}
+
+// WithFlushInterval causes an Encoder to write a block once the first row in
+// it was encoded at least d ago, even if it is smaller than approxBlockSize.
+// This is useful for long-lived writers where data arrives slowly. The
+// interval is checked when Encode is called. If rows may stop arriving, call
+// FlushIfDue periodically, for example from a time.Ticker, so the last block
+// isn't held back until the next row. It is ignored by FileWriter.
+func WithFlushInterval(d time.Duration) WriteOption {
+	return func(wc *writeConfig) {
+		wc.flushInterval = d
+	}
+}
+
+// WithCloseWriter causes Encoder.Close to close the underlying writer, if it
+// implements io.Closer. It is ignored by FileWriter.
+func WithCloseWriter() WriteOption {
+	return func(wc *writeConfig) {
+		wc.closeWriter = true
+	}
 }
 
 // NewEncoderFor returns a new Encoder. Data will be written to w in Avro format,
 // including a schema header. The data will be compressed using the specified
 // compression algorithm. Data is written in blocks of at least approxBlockSize
 // bytes. A block is written when it reaches that size, or when Flush is called.
-func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize int) (*Encoder[T], error) {
-	var t T
-
+func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize int, opts ...WriteOption) (*Encoder[T], error) {
 	typ := reflect.TypeFor[T]()
 	if typ.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("only structs are supported, got %v", typ)
@@ -35,7 +87,26 @@ func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize
 		return nil, fmt.Errorf("generating schema: %w", err)
 	}
 
-	c, err := s.Codec(t)
+	return NewEncoderForSchema[T](w, s, compression, approxBlockSize, opts...)
+}
+
+// NewEncoderForSchema is like NewEncoderFor, but the data is written with the
+// given schema rather than one derived from T. Use this when the file must
+// conform to a schema you don't control, for example the schema of a table you
+// are loading the file into. Struct fields are matched to schema fields as when
+// reading, by name or alias. Schema fields with no matching struct field are
+// written with their default value, or as null if they are nullable.
+// NewEncoderForSchema returns an error if T can't be written with the schema,
+// for example if a schema field with no matching struct field has no default
+// and is not nullable, or a struct field has a type that can't be converted to
+// the schema type.
+func NewEncoderForSchema[T any](w io.Writer, s Schema, compression Compression, approxBlockSize int, opts ...WriteOption) (*Encoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	c, err := buildWriteCodec(s, typ)
 	if err != nil {
 		return nil, fmt.Errorf("generating codec: %w", err)
 	}
@@ -45,7 +116,7 @@ func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize
 		return nil, fmt.Errorf("marshaling schema: %w", err)
 	}
 
-	fw, err := NewFileWriter(schemaBytes, compression)
+	fw, err := NewFileWriter(schemaBytes, compression, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating file writer: %w", err)
 	}
@@ -54,6 +125,58 @@ func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize
 		return nil, fmt.Errorf("writing file header: %w", err)
 	}
 
+	return newEncoder[T](s, c, fw, w, approxBlockSize, newWriteConfig(opts)), nil
+}
+
+// NewAppendEncoder returns an Encoder that adds records to the end of the
+// existing AVRO file in rws. It reads the file header to find the schema,
+// compression codec and sync marker, and these are used for the new records.
+// T is matched to the schema in the file in the same way as for
+// NewEncoderForSchema, so the file need not have been written using T.
+//
+//	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	enc, err := avro.NewAppendEncoder[myrecord](f, 100_000)
+//	...
+func NewAppendEncoder[T any](rws io.ReadWriteSeeker, approxBlockSize int, opts ...WriteOption) (*Encoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking to start of file: %w", err)
+	}
+	fh, err := readFileHeader(bufio.NewReader(rws))
+	if err != nil {
+		return nil, fmt.Errorf("reading file header: %w", err)
+	}
+
+	s, err := fh.schema()
+	if err != nil {
+		return nil, err
+	}
+	c, err := buildWriteCodec(s, typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+
+	fw, err := NewFileWriterForHeader(fh, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating file writer: %w", err)
+	}
+
+	if _, err := rws.Seek(0, io.SeekEnd); err != nil {
+		return nil, fmt.Errorf("seeking to end of file: %w", err)
+	}
+
+	return newEncoder[T](s, c, fw, rws, approxBlockSize, newWriteConfig(opts)), nil
+}
+
+func newEncoder[T any](s Schema, c Codec, fw *FileWriter, w io.Writer, approxBlockSize int, wc writeConfig) *Encoder[T] {
 	return &Encoder[T]{
 		schema: s,
 		codec:  c,
@@ -61,16 +184,34 @@ func NewEncoderFor[T any](w io.Writer, compression Compression, approxBlockSize
 		w:      w,
 
 		approxBlockSize: approxBlockSize,
-		wb:              NewWriteBuf(make([]byte, 0, approxBlockSize)),
-	}, nil
+		maxBlockRows:    wc.maxBlockRows,
+		flushInterval:   wc.flushInterval,
+		closeWriter:     wc.closeWriter,
+
+		wb: NewWriteBuf(make([]byte, 0, approxBlockSize)),
+	}
 }
 
-// Encode writes a new row to the Avro file.
+var errEncoderClosed = errors.New("encoder is closed")
+
+// Encode writes a new row to the Avro file. If the row cannot be encoded an
+// error is returned and the row is discarded.
 func (e *Encoder[T]) Encode(v *T) error {
+	if e.closed {
+		return errEncoderClosed
+	}
+	start := e.wb.Len()
 	e.codec.Write(e.wb, unsafe.Pointer(v))
+	if err := e.wb.Err(); err != nil {
+		e.wb.truncate(start)
+		return fmt.Errorf("encoding row: %w", err)
+	}
 	e.count++
+	if e.count == 1 && e.flushInterval > 0 {
+		e.blockStart = time.Now()
+	}
 
-	if e.wb.Len() >= e.approxBlockSize {
+	if e.blockFull() {
 		if err := e.Flush(); err != nil {
 			return fmt.Errorf("flushing: %w", err)
 		}
@@ -79,15 +220,68 @@ func (e *Encoder[T]) Encode(v *T) error {
 	return nil
 }
 
+// blockFull returns true if the current block should be written.
+func (e *Encoder[T]) blockFull() bool {
+	return e.wb.Len() >= e.approxBlockSize ||
+		(e.maxBlockRows > 0 && e.count >= e.maxBlockRows) ||
+		(e.flushInterval > 0 && time.Since(e.blockStart) >= e.flushInterval)
+}
+
+// FlushIfDue writes the current block if the interval set with
+// WithFlushInterval has passed since its first row was encoded. It does nothing
+// if no interval was set. Like the other methods of Encoder it must not be
+// called concurrently with them.
+func (e *Encoder[T]) FlushIfDue() error {
+	if e.closed {
+		return errEncoderClosed
+	}
+	if e.count == 0 || e.flushInterval <= 0 || time.Since(e.blockStart) < e.flushInterval {
+		return nil
+	}
+	return e.Flush()
+}
+
 // Flush writes any buffered data to the underlying writer. It completes the
-// current block. It must be called before closing the underlying file.
+// current block. It must be called before closing the underlying file, or use
+// Close instead.
 func (e *Encoder[T]) Flush() error {
+	if e.closed {
+		return errEncoderClosed
+	}
 	if e.count > 0 {
-		if err := e.fw.WriteBlock(e.w, e.count, e.wb.Bytes()); err != nil {
+		n, err := e.fw.writeBlock(e.w, e.count, e.wb.Bytes())
+		if err != nil {
 			return fmt.Errorf("writing block: %w", err)
 		}
+		e.stats.Rows += int64(e.count)
+		e.stats.Blocks++
+		e.stats.UncompressedBytes += int64(e.wb.Len())
+		e.stats.CompressedBytes += int64(n)
 		e.count = 0
 		e.wb.Reset()
 	}
 	return nil
 }
+
+// Close flushes any buffered data. If WithCloseWriter was passed when the
+// Encoder was created it then closes the underlying writer, if it implements
+// io.Closer. The Encoder can't be used after Close is called, even if the flush
+// fails, and the writer is closed regardless. Calling Close again does nothing.
+func (e *Encoder[T]) Close() error {
+	if e.closed {
+		return nil
+	}
+	err := e.Flush()
+	e.closed = true
+	if c, ok := e.w.(io.Closer); ok && e.closeWriter {
+		if cerr := c.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("closing writer: %w", cerr))
+		}
+	}
+	return err
+}
+
+// Stats returns counts of the data written so far.
+func (e *Encoder[T]) Stats() EncoderStats {
+	return e.stats
+}
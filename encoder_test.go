@@ -1,8 +1,14 @@
 package avro_test
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
@@ -102,3 +108,903 @@ func TestEncoder(t *testing.T) {
 		t.Fatalf("result not as expected. %s", diff)
 	}
 }
+
+type direction int
+
+func TestEncoderEnum(t *testing.T) {
+	type myStruct struct {
+		Colour    string    `json:"colour" avro:"enum=RED|GREEN|BLUE"`
+		Direction direction `json:"direction,omitempty" avro:"enum=UP|DOWN"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	enc, err := avro.NewEncoderFor[myStruct](buf, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []myStruct{
+		{Colour: "RED", Direction: 1},
+		{Colour: "BLUE"},
+	}
+
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Encode(&myStruct{Colour: "PURPLE"}); err == nil {
+		t.Fatal("expected an error encoding an unknown symbol")
+	}
+
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []myStruct
+	if err := avro.ReadFileFor(buf, func(val *myStruct, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(contents, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+type numberOrWord struct {
+	Number *int64  `avro:"branch=long"`
+	Word   *string `avro:"branch=string"`
+	Null   bool    `avro:"branch=null"`
+}
+
+func TestEncoderUnionStruct(t *testing.T) {
+	type myStruct struct {
+		Value numberOrWord `json:"value"`
+	}
+
+	s, err := avro.SchemaForType(myStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(avro.Schema{
+		Type:  "union",
+		Union: []avro.Schema{{Type: "long"}, {Type: "string"}, {Type: "null"}},
+	}, s.Object.Fields[0].Type); diff != "" {
+		t.Fatal(diff)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc, err := avro.NewEncoderFor[myStruct](buf, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := int64(42)
+	w := "hat"
+	contents := []myStruct{
+		{Value: numberOrWord{Number: &n}},
+		{Value: numberOrWord{Word: &w}},
+		{},
+	}
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []myStruct
+	if err := avro.ReadFileFor(buf, func(val *myStruct, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(contents, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+type numberOrWordNotNull struct {
+	Number *int64  `avro:"branch=long"`
+	Word   *string `avro:"branch=string"`
+}
+
+func TestEncoderUnionStructPointer(t *testing.T) {
+	type myStruct struct {
+		Value   *numberOrWord        `json:"value"`
+		NotNull *numberOrWordNotNull `json:"not_null"`
+	}
+
+	s, err := avro.SchemaForType(myStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(avro.Schema{
+		Type:  "union",
+		Union: []avro.Schema{{Type: "long"}, {Type: "string"}, {Type: "null"}},
+	}, s.Object.Fields[0].Type); diff != "" {
+		t.Fatal(diff)
+	}
+	// A nil pointer is null, so the union gains a null branch
+	if diff := cmp.Diff(avro.Schema{
+		Type:  "union",
+		Union: []avro.Schema{{Type: "null"}, {Type: "long"}, {Type: "string"}},
+	}, s.Object.Fields[1].Type); diff != "" {
+		t.Fatal(diff)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc, err := avro.NewEncoderFor[myStruct](buf, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := int64(42)
+	w := "hat"
+	contents := []myStruct{
+		{Value: &numberOrWord{Number: &n}, NotNull: &numberOrWordNotNull{Word: &w}},
+		{Value: &numberOrWord{Word: &w}},
+		{NotNull: &numberOrWordNotNull{Number: &n}},
+		{},
+	}
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []myStruct
+	if err := avro.ReadFileFor(buf, func(val *myStruct, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(contents, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+func TestEncoderSchemaResolution(t *testing.T) {
+	type oldStruct struct {
+		Name  string  `json:"name"`
+		Count int32   `json:"count"`
+		Score float32 `json:"score"`
+		Extra string  `json:"extra"`
+	}
+	type newStruct struct {
+		Count int64   `json:"count"`
+		Name  string  `json:"name"`
+		Score float64 `json:"score"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc, err := avro.NewEncoderFor[oldStruct](buf, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := []oldStruct{
+		{Name: "jim", Count: 3, Score: 0.5, Extra: "hat"},
+		{Name: "sue", Count: -7, Score: 2},
+	}
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []newStruct
+	if err := avro.ReadFileFor(buf, func(val *newStruct, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}, avro.WithSchemaResolution()); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []newStruct{
+		{Name: "jim", Count: 3, Score: 0.5},
+		{Name: "sue", Count: -7, Score: 2},
+	}
+	if diff := cmp.Diff(exp, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+type encoderTree struct {
+	Name     string        `json:"name"`
+	Children []encoderTree `json:"children"`
+	Parent   *encoderTree  `json:"parent,omitempty"`
+}
+
+func TestEncoderRecursive(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc, err := avro.NewEncoderFor[encoderTree](buf, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []encoderTree{
+		{Name: "root", Children: []encoderTree{{Name: "a", Children: []encoderTree{{Name: "a1"}}}, {Name: "b"}}},
+		{Name: "child", Parent: &encoderTree{Name: "parent"}},
+	}
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []encoderTree
+	if err := avro.ReadFileFor(buf, func(val *encoderTree, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(contents, actual, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+func TestAppendEncoder(t *testing.T) {
+	type record struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	for _, compression := range []avro.Compression{avro.CompressionNull, avro.CompressionDeflate, avro.CompressionSnappy, avro.CompressionZstandard} {
+		t.Run(string(compression), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "test.avro")
+
+			f, err := os.Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			enc, err := avro.NewEncoderFor[record](f, compression, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Encode(&record{ID: 1, Name: "one"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, r := range []record{{ID: 2, Name: "two"}, {ID: 3, Name: "three"}} {
+				f, err := os.OpenFile(filename, os.O_RDWR, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				enc, err := avro.NewAppendEncoder[record](f, 1000)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := enc.Encode(&r); err != nil {
+					t.Fatal(err)
+				}
+				if err := enc.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				if err := f.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err = os.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var got []record
+			if err := avro.ReadFileFor(bufio.NewReader(f), func(val *record, rb *avro.ResourceBank) error {
+				got = append(got, record{ID: val.ID, Name: string([]byte(val.Name))})
+				rb.Close()
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			exp := []record{{ID: 1, Name: "one"}, {ID: 2, Name: "two"}, {ID: 3, Name: "three"}}
+			if diff := cmp.Diff(exp, got); diff != "" {
+				t.Fatalf("records not as expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppendEncoderMismatch(t *testing.T) {
+	type record struct {
+		ID int64 `json:"id"`
+	}
+	type other struct {
+		ID string `json:"id"`
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.avro")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := avro.NewEncoderFor[record](f, avro.CompressionNull, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := avro.NewAppendEncoder[other](f, 1000); err == nil {
+		t.Fatal("expected an error for a mismatched type")
+	}
+
+	empty, err := os.Create(filepath.Join(t.TempDir(), "empty.avro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if _, err := avro.NewAppendEncoder[record](empty, 1000); err == nil {
+		t.Fatal("expected an error for an empty file")
+	}
+}
+
+func TestAppendEncoderForSchema(t *testing.T) {
+	schema := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name: "Event",
+			Fields: []avro.SchemaRecordField{
+				{Name: "id", Type: avro.Schema{Type: "long"}},
+				{Name: "name", Type: avro.Schema{Type: "string"}},
+			},
+		},
+	}
+	// The record name and field order differ from the schema
+	type event struct {
+		Name string `json:"name"`
+		ID   int64  `json:"id"`
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.avro")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc, err := avro.NewEncoderForSchema[event](f, schema, avro.CompressionNull, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&event{ID: 1, Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err = avro.NewAppendEncoder[event](f, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&event{ID: 2, Name: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var got []event
+	if err := avro.ReadFileFor(bufio.NewReader(f), func(val *event, rb *avro.ResourceBank) error {
+		got = append(got, event{ID: val.ID, Name: string([]byte(val.Name))})
+		rb.Close()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []event{{ID: 1, Name: "one"}, {ID: 2, Name: "two"}}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("records not as expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoderForSchema(t *testing.T) {
+	schema := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name: "Event",
+			Fields: []avro.SchemaRecordField{
+				{Name: "id", Type: avro.Schema{Type: "long"}},
+				{Name: "kind", Type: avro.Schema{
+					Type:   "enum",
+					Object: &avro.SchemaObject{Name: "Kind", Symbols: []string{"CLICK", "VIEW"}},
+				}},
+				{Name: "score", Type: avro.Schema{Type: "float"}},
+			},
+		},
+	}
+
+	// Fields are in a different order from the schema, and Note isn't in the
+	// schema at all.
+	type event struct {
+		Kind  string  `json:"kind"`
+		Note  string  `json:"note"`
+		Score float32 `json:"score"`
+		ID    int     `json:"id"`
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderForSchema[event](&buf, schema, avro.CompressionNull, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := []event{
+		{ID: 1, Kind: "CLICK", Score: 1.5, Note: "not written"},
+		{ID: 2, Kind: "VIEW"},
+	}
+	for i := range contents {
+		if err := enc.Encode(&contents[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Encode(&event{Kind: "HOVER"}); err == nil {
+		t.Fatal("expected an error encoding an unknown symbol")
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	fr, err := avro.NewFileReader[event](bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Close()
+
+	have, err := fr.Schema().CanonicalForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := schema.CanonicalForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Fatalf("file schema %s, expected %s", have, want)
+	}
+
+	var actual []event
+	for val, err := range fr.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, *val)
+	}
+	contents[0].Note = ""
+	if diff := cmp.Diff(contents, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+func TestEncoderForSchemaErrors(t *testing.T) {
+	schema := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name: "Event",
+			Fields: []avro.SchemaRecordField{
+				{Name: "id", Type: avro.Schema{Type: "long"}},
+				{Name: "name", Type: avro.Schema{Type: "string"}},
+			},
+		},
+	}
+
+	type missingField struct {
+		ID int `json:"id"`
+	}
+	if _, err := avro.NewEncoderForSchema[missingField](io.Discard, schema, avro.CompressionNull, 1000); err == nil {
+		t.Error("expected an error for a schema field with no struct field")
+	}
+
+	type wrongType struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if _, err := avro.NewEncoderForSchema[wrongType](io.Discard, schema, avro.CompressionNull, 1000); err == nil {
+		t.Error("expected an error for a struct field of the wrong type")
+	}
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+	fail   bool
+}
+
+func (c *closeRecorder) Write(p []byte) (int, error) {
+	if c.fail {
+		return 0, errors.New("disk full")
+	}
+	return c.Buffer.Write(p)
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestEncoderClose(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	for _, closeWriter := range []bool{false, true} {
+		var opts []avro.WriteOption
+		if closeWriter {
+			opts = append(opts, avro.WithCloseWriter())
+		}
+		var w closeRecorder
+		enc, err := avro.NewEncoderFor[record](&w, avro.CompressionNull, 10_000, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(&record{Name: "jim"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if w.closed != closeWriter {
+			t.Errorf("closeWriter %t: writer closed is %t", closeWriter, w.closed)
+		}
+		if err := enc.Close(); err != nil {
+			t.Errorf("second close: %v", err)
+		}
+		if err := enc.Encode(&record{Name: "sheila"}); err == nil {
+			t.Error("expected an error encoding after close")
+		}
+
+		var actual []record
+		if err := avro.ReadFileFor(bufio.NewReader(&w), func(val *record, rb *avro.ResourceBank) error {
+			actual = append(actual, *val)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]record{{Name: "jim"}}, actual); diff != "" {
+			t.Fatalf("result not as expected. %s", diff)
+		}
+	}
+}
+
+func TestEncoderCloseFlushError(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	var w closeRecorder
+	enc, err := avro.NewEncoderFor[record](&w, avro.CompressionNull, 10_000, avro.WithCloseWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&record{Name: "jim"}); err != nil {
+		t.Fatal(err)
+	}
+	w.fail = true
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected an error flushing the last block")
+	}
+	if !w.closed {
+		t.Error("writer not closed")
+	}
+	if err := enc.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	if err := enc.Encode(&record{Name: "sheila"}); err == nil {
+		t.Error("expected an error encoding after close")
+	}
+}
+
+func TestEncoderFlushIfDue(t *testing.T) {
+	type record struct {
+		V int `json:"v"`
+	}
+
+	for _, test := range []struct {
+		name     string
+		opts     []avro.WriteOption
+		wantRows int64
+	}{
+		{name: "no interval", wantRows: 0},
+		{name: "not due", opts: []avro.WriteOption{avro.WithFlushInterval(time.Hour)}, wantRows: 0},
+		{name: "due", opts: []avro.WriteOption{avro.WithFlushInterval(10 * time.Millisecond)}, wantRows: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := avro.NewEncoderFor[record](&buf, avro.CompressionNull, 10_000, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.FlushIfDue(); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Encode(&record{V: 1}); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(20 * time.Millisecond)
+			if err := enc.FlushIfDue(); err != nil {
+				t.Fatal(err)
+			}
+			if rows := enc.Stats().Rows; rows != test.wantRows {
+				t.Fatalf("%d rows written, expected %d", rows, test.wantRows)
+			}
+		})
+	}
+}
+
+func TestEncoderBlockLimits(t *testing.T) {
+	type record struct {
+		V int `json:"v"`
+	}
+
+	tests := []struct {
+		name       string
+		opt        avro.WriteOption
+		rows       int
+		wantBlocks []int64
+	}{
+		{name: "max rows", opt: avro.WithMaxBlockRows(3), rows: 7, wantBlocks: []int64{3, 3, 1}},
+		{name: "interval", opt: avro.WithFlushInterval(time.Nanosecond), rows: 3, wantBlocks: []int64{1, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := avro.NewEncoderFor[record](&buf, avro.CompressionNull, 10_000, test.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range test.rows {
+				if err := enc.Encode(&record{V: i}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			fr, err := avro.NewFileReader[record](bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fr.Close()
+			var counts []int64
+			var size int64
+			for b, err := range fr.Blocks() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				counts = append(counts, b.Count)
+				size += int64(len(b.Data))
+			}
+			if diff := cmp.Diff(test.wantBlocks, counts); diff != "" {
+				t.Fatalf("block counts not as expected. %s", diff)
+			}
+
+			exp := avro.EncoderStats{
+				Rows:              int64(test.rows),
+				Blocks:            int64(len(test.wantBlocks)),
+				UncompressedBytes: size,
+				CompressedBytes:   size,
+			}
+			if diff := cmp.Diff(exp, enc.Stats()); diff != "" {
+				t.Fatalf("stats not as expected. %s", diff)
+			}
+		})
+	}
+}
+
+func TestEncoderStatsCompressed(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[record](&buf, avro.CompressionDeflate, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		if err := enc.Encode(&record{Name: "the same name every time"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	stats := enc.Stats()
+	if stats.Rows != 100 || stats.Blocks != 1 || stats.UncompressedBytes != 2500 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.CompressedBytes == 0 || stats.CompressedBytes >= stats.UncompressedBytes {
+		t.Fatalf("unexpected compressed size %d", stats.CompressedBytes)
+	}
+}
+
+func TestEncoderErrorPath(t *testing.T) {
+	type line struct {
+		Count int64 `json:"count"`
+	}
+	type address struct {
+		Lines []line `json:"lines"`
+	}
+	type person struct {
+		Name    string   `json:"name"`
+		Address *address `json:"address"`
+	}
+
+	schema := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name: "Person",
+			Fields: []avro.SchemaRecordField{
+				{Name: "name", Type: avro.Schema{Type: "string"}},
+				{Name: "address", Type: avro.Schema{
+					Type: "record",
+					Object: &avro.SchemaObject{
+						Name: "Address",
+						Fields: []avro.SchemaRecordField{
+							{Name: "lines", Type: avro.Schema{
+								Type: "array",
+								Object: &avro.SchemaObject{
+									Items: avro.Schema{
+										Type: "record",
+										Object: &avro.SchemaObject{
+											Name: "Line",
+											Fields: []avro.SchemaRecordField{
+												{Name: "count", Type: avro.Schema{Type: "int"}},
+											},
+										},
+									},
+								},
+							}},
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderForSchema[person](&buf, schema, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   person
+		path string
+	}{
+		{
+			name: "int out of range",
+			in:   person{Name: "a", Address: &address{Lines: []line{{Count: 1}, {Count: 1 << 40}}}},
+			path: "address.lines[1].count",
+		},
+		{
+			name: "nil pointer",
+			in:   person{Name: "b"},
+			path: "address",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := enc.Encode(&test.in)
+			var ee *avro.EncodeError
+			if !errors.As(err, &ee) {
+				t.Fatalf("expected an EncodeError, got %v", err)
+			}
+			if path := ee.Path(); path != test.path {
+				t.Fatalf("path is %q, expected %q", path, test.path)
+			}
+		})
+	}
+
+	// The bad rows should not have corrupted the file
+	good := person{Name: "c", Address: &address{Lines: []line{{Count: 3}}}}
+	if err := enc.Encode(&good); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var actual []person
+	if err := avro.ReadFileFor(bufio.NewReader(&buf), func(val *person, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]person{good}, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
+
+func TestEncoderForSchemaUnmappedFields(t *testing.T) {
+	schema := avro.Schema{
+		Type: "record",
+		Object: &avro.SchemaObject{
+			Name: "Event",
+			Fields: []avro.SchemaRecordField{
+				{Name: "id", Type: avro.Schema{Type: "long"}},
+				{Name: "source", Type: avro.Schema{Type: "string"}, Default: []byte(`"web"`)},
+				{Name: "weight", Type: avro.Schema{Type: "double"}, Default: []byte(`1.5`)},
+				{Name: "note", Type: avro.Schema{Type: "union", Union: []avro.Schema{{Type: "null"}, {Type: "string"}}}},
+				{Name: "tags", Type: avro.Schema{
+					Type:   "array",
+					Object: &avro.SchemaObject{Items: avro.Schema{Type: "string"}},
+				}, Default: []byte(`["a","b"]`)},
+			},
+		},
+	}
+
+	type event struct {
+		ID int64 `json:"id"`
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderForSchema[event](&buf, schema, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if err := enc.Encode(&event{ID: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	type fullEvent struct {
+		ID     int64    `json:"id"`
+		Source string   `json:"source"`
+		Weight float64  `json:"weight"`
+		Note   *string  `json:"note"`
+		Tags   []string `json:"tags"`
+	}
+	var actual []fullEvent
+	if err := avro.ReadFileFor(bufio.NewReader(&buf), func(val *fullEvent, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	exp := []fullEvent{
+		{ID: 0, Source: "web", Weight: 1.5, Tags: []string{"a", "b"}},
+		{ID: 1, Source: "web", Weight: 1.5, Tags: []string{"a", "b"}},
+	}
+	if diff := cmp.Diff(exp, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+}
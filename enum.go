@@ -0,0 +1,227 @@
+package avro
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// enumSymbols holds the symbols of an enum and an index to find a symbol's
+// position.
+type enumSymbols struct {
+	name    string
+	symbols []string
+	index   map[string]int
+}
+
+func newEnumSymbols(schema Schema) (enumSymbols, error) {
+	if schema.Object == nil || len(schema.Object.Symbols) == 0 {
+		return enumSymbols{}, fmt.Errorf("enum schema has no symbols")
+	}
+	es := enumSymbols{
+		name:    schema.Object.Name,
+		symbols: schema.Object.Symbols,
+		index:   make(map[string]int, len(schema.Object.Symbols)),
+	}
+	for i, s := range es.symbols {
+		es.index[s] = i
+	}
+	return es, nil
+}
+
+func (es *enumSymbols) readIndex(r *ReadBuf) (int, error) {
+	index, err := r.Varint()
+	if err != nil {
+		return 0, fmt.Errorf("failed reading enum index. %w", err)
+	}
+	if index < 0 || index >= int64(len(es.symbols)) {
+		return 0, fmt.Errorf("enum index %d out of range (%d symbols)", index, len(es.symbols))
+	}
+	return int(index), nil
+}
+
+func (es *enumSymbols) writeSymbol(w *WriteBuf, s string) {
+	index, ok := es.index[s]
+	if !ok {
+		w.SetError(fmt.Errorf("%q is not a symbol of enum %s", s, es.name))
+		return
+	}
+	w.Varint(int64(index))
+}
+
+// enumSetter is implemented by the enum codecs. setIndex sets the value at p
+// to represent the symbol at index.
+type enumSetter interface {
+	setIndex(p unsafe.Pointer, index int) error
+}
+
+func (es *enumSymbols) Skip(r *ReadBuf) error {
+	_, err := es.readIndex(r)
+	return err
+}
+
+// enumStringCodec decodes an AVRO enum into a string containing the symbol
+type enumStringCodec struct {
+	enumSymbols
+	omitEmpty bool
+}
+
+func (c *enumStringCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := c.readIndex(r)
+	if err != nil {
+		return err
+	}
+	return c.setIndex(p, index)
+}
+
+func (c *enumStringCodec) setIndex(p unsafe.Pointer, index int) error {
+	*(*string)(p) = c.symbols[index]
+	return nil
+}
+
+func (c *enumStringCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(stringType)
+}
+
+func (c *enumStringCodec) Omit(p unsafe.Pointer) bool {
+	return c.omitEmpty && len(*(*string)(p)) == 0
+}
+
+func (c *enumStringCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	c.writeSymbol(w, *(*string)(p))
+}
+
+// enumIntCodec decodes an AVRO enum into an integer containing the index of
+// the symbol.
+type enumIntCodec[T int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64] struct {
+	enumSymbols
+	rtype     reflect.Type
+	omitEmpty bool
+}
+
+func (c *enumIntCodec[T]) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := c.readIndex(r)
+	if err != nil {
+		return err
+	}
+	return c.setIndex(p, index)
+}
+
+func (c *enumIntCodec[T]) setIndex(p unsafe.Pointer, index int) error {
+	*(*T)(p) = T(index)
+	return nil
+}
+
+func (c *enumIntCodec[T]) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(c.rtype)
+}
+
+func (c *enumIntCodec[T]) Omit(p unsafe.Pointer) bool {
+	return c.omitEmpty && *(*T)(p) == 0
+}
+
+func (c *enumIntCodec[T]) Write(w *WriteBuf, p unsafe.Pointer) {
+	v := *(*T)(p)
+	// Negative values become very large when converted to uint64, so this
+	// catches those too.
+	if uint64(v) >= uint64(len(c.symbols)) {
+		w.SetError(fmt.Errorf("enum index %d out of range for enum %s (%d symbols)", v, c.name, len(c.symbols)))
+		return
+	}
+	w.Varint(int64(v))
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// enumTextCodec decodes an AVRO enum into a type that implements
+// encoding.TextUnmarshaler. If the type also implements encoding.TextMarshaler
+// that is used when encoding.
+type enumTextCodec struct {
+	enumSymbols
+	rtype     reflect.Type
+	omitEmpty bool
+}
+
+func (c *enumTextCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := c.readIndex(r)
+	if err != nil {
+		return err
+	}
+	return c.setIndex(p, index)
+}
+
+func (c *enumTextCodec) setIndex(p unsafe.Pointer, index int) error {
+	u := reflect.NewAt(c.rtype, p).Interface().(encoding.TextUnmarshaler)
+	if err := u.UnmarshalText([]byte(c.symbols[index])); err != nil {
+		return fmt.Errorf("unmarshalling enum symbol %q into %s: %w", c.symbols[index], c.rtype, err)
+	}
+	return nil
+}
+
+func (c *enumTextCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(c.rtype)
+}
+
+func (c *enumTextCodec) Omit(p unsafe.Pointer) bool {
+	return c.omitEmpty && reflect.NewAt(c.rtype, p).Elem().IsZero()
+}
+
+func (c *enumTextCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	m, ok := reflect.NewAt(c.rtype, p).Interface().(encoding.TextMarshaler)
+	if !ok {
+		w.SetError(fmt.Errorf("%s does not implement encoding.TextMarshaler so cannot be written as enum %s", c.rtype, c.name))
+		return
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		w.SetError(fmt.Errorf("marshalling %s as enum %s: %w", c.rtype, c.name, err))
+		return
+	}
+	c.writeSymbol(w, string(text))
+}
+
+func buildEnumCodec(schema Schema, typ reflect.Type, omit bool) (Codec, error) {
+	es, err := newEnumSymbols(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == nil {
+		return &enumStringCodec{enumSymbols: es}, nil
+	}
+
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return &enumTextCodec{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return &enumStringCodec{enumSymbols: es, omitEmpty: omit}, nil
+	case reflect.Int:
+		return &enumIntCodec[int]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Int8:
+		return &enumIntCodec[int8]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Int16:
+		return &enumIntCodec[int16]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Int32:
+		return &enumIntCodec[int32]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Int64:
+		return &enumIntCodec[int64]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Uint:
+		return &enumIntCodec[uint]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Uint8:
+		return &enumIntCodec[uint8]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Uint16:
+		return &enumIntCodec[uint16]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Uint32:
+		return &enumIntCodec[uint32]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	case reflect.Uint64:
+		return &enumIntCodec[uint64]{enumSymbols: es, rtype: typ, omitEmpty: omit}, nil
+	}
+
+	return nil, fmt.Errorf("type for enum must be a string, an integer or implement encoding.TextUnmarshaler, not %s", typ)
+}
@@ -0,0 +1,192 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var colourSchema = Schema{
+	Type: "enum",
+	Object: &SchemaObject{
+		Name:    "Colour",
+		Symbols: []string{"RED", "GREEN", "BLUE"},
+	},
+}
+
+type colour int
+
+type textColour struct{ name string }
+
+func (c *textColour) UnmarshalText(text []byte) error {
+	if string(text) == "GREEN" {
+		return fmt.Errorf("no green")
+	}
+	c.name = string(text)
+	return nil
+}
+
+func (c textColour) MarshalText() ([]byte, error) {
+	return []byte(c.name), nil
+}
+
+func TestEnumCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		exp  string
+	}{
+		{name: "first", data: []byte{0}, exp: "RED"},
+		{name: "last", data: []byte{4}, exp: "BLUE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" string", func(t *testing.T) {
+			c, err := buildEnumCodec(colourSchema, reflect.TypeFor[string](), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReadBuf(test.data)
+			var actual string
+			if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if actual != test.exp {
+				t.Fatalf("got %q, expected %q", actual, test.exp)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&actual))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.data, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run(test.name+" int", func(t *testing.T) {
+			c, err := buildEnumCodec(colourSchema, reflect.TypeFor[colour](), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReadBuf(test.data)
+			var actual colour
+			if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if sym := colourSchema.Object.Symbols[actual]; sym != test.exp {
+				t.Fatalf("got %q, expected %q", sym, test.exp)
+			}
+
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&actual))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.data, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run(test.name+" text", func(t *testing.T) {
+			c, err := buildEnumCodec(colourSchema, reflect.TypeFor[textColour](), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReadBuf(test.data)
+			var actual textColour
+			if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if actual.name != test.exp {
+				t.Fatalf("got %q, expected %q", actual.name, test.exp)
+			}
+
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&actual))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.data, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run(test.name+" skip", func(t *testing.T) {
+			c, err := buildEnumCodec(colourSchema, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReadBuf(test.data)
+			if err := c.Skip(r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+		})
+	}
+}
+
+func TestEnumCodecErrors(t *testing.T) {
+	t.Run("index out of range", func(t *testing.T) {
+		c, err := buildEnumCodec(colourSchema, reflect.TypeFor[string](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual string
+		if err := c.Read(NewReadBuf([]byte{6}), unsafe.Pointer(&actual)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("unmarshal text fails", func(t *testing.T) {
+		c, err := buildEnumCodec(colourSchema, reflect.TypeFor[textColour](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual textColour
+		if err := c.Read(NewReadBuf([]byte{2}), unsafe.Pointer(&actual)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		c, err := buildEnumCodec(colourSchema, reflect.TypeFor[string](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWriteBuf(nil)
+		v := "PURPLE"
+		c.Write(w, unsafe.Pointer(&v))
+		if err := w.Err(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("index too large", func(t *testing.T) {
+		c, err := buildEnumCodec(colourSchema, reflect.TypeFor[colour](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWriteBuf(nil)
+		v := colour(3)
+		c.Write(w, unsafe.Pointer(&v))
+		if err := w.Err(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		if _, err := buildEnumCodec(colourSchema, reflect.TypeFor[float64](), false); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -2,20 +2,17 @@ package avro
 
 import (
 	"bufio"
-	"bytes"
-	"compress/flate"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"hash/crc32"
 	"io"
 	"iter"
 	"os"
 	"reflect"
+	"strings"
 	"unsafe"
 
 	"github.com/go-json-experiment/json"
-	"github.com/golang/snappy"
 )
 
 // FileHeader represents an AVRO file header
@@ -25,18 +22,30 @@ type FileHeader struct {
 	Sync  [16]byte          `json:"sync"`
 }
 
-func (fh FileHeader) decoder() (compressionCodec, error) {
-	if compress, ok := fh.Meta["avro.codec"]; ok {
-		switch string(compress) {
-		case "null":
-			return nullCompression{}, nil
-		case "deflate":
-			return &deflate{}, nil
-		case "snappy":
-			return &snappyCodec{}, nil
-		default:
-			return nil, fmt.Errorf("compression codec %s not supported", string(compress))
+// reservedMetaPrefix is the prefix for metadata keys reserved by the AVRO
+// specification, such as avro.schema and avro.codec.
+const reservedMetaPrefix = "avro."
+
+// UserMeta returns the metadata from the header that isn't reserved by the
+// AVRO specification. These are the entries with keys that don't start with
+// "avro.". It returns nil if there are none.
+func (fh FileHeader) UserMeta() map[string][]byte {
+	var meta map[string][]byte
+	for k, v := range fh.Meta {
+		if strings.HasPrefix(k, reservedMetaPrefix) {
+			continue
+		}
+		if meta == nil {
+			meta = make(map[string][]byte)
 		}
+		meta[k] = v
+	}
+	return meta
+}
+
+func (fh FileHeader) decoder() (CompressionCodec, error) {
+	if compress, ok := fh.Meta["avro.codec"]; ok {
+		return newCompressionCodec(Compression(compress), DefaultCompressionLevel)
 	}
 	return nullCompression{}, nil
 }
@@ -133,11 +142,71 @@ type Reader interface {
 //	       return err
 //	}
 
-func ReadFileFor[T any](r Reader, cb func(val *T, rb *ResourceBank) error) error {
+func ReadFileFor[T any](r Reader, cb func(val *T, rb *ResourceBank) error, opts ...ReadOption) error {
 	var t T
 	return ReadFile(r, t, func(val unsafe.Pointer, rb *ResourceBank) error {
 		return cb((*T)(val), rb)
-	})
+	}, opts...)
+}
+
+// ReadOption is an option for reading AVRO data, used by ReadFile,
+// ReadFileFor, NewFileReader and others.
+type ReadOption func(*readConfig)
+
+type readConfig struct {
+	resolve bool
+	reader  Schema
+
+	workers   int
+	unordered bool
+
+	header *FileHeader
+}
+
+// WithReaderSchema causes data to be read using AVRO schema resolution with
+// reader as the reader's schema. The schema from the file is used as the
+// writer's schema. Use this when the file may have been written with an older
+// or newer version of the schema: fields are matched by name, numeric types
+// are promoted, and enum symbols and union branches are mapped between the
+// schemas.
+func WithReaderSchema(reader Schema) ReadOption {
+	return func(rc *readConfig) {
+		rc.resolve = true
+		rc.reader = reader
+	}
+}
+
+// WithSchemaResolution is like WithReaderSchema, but the reader's schema is
+// derived from the type being read into using SchemaForType.
+func WithSchemaResolution() ReadOption {
+	return func(rc *readConfig) {
+		rc.resolve = true
+	}
+}
+
+// WithFileHeader causes the header of the file being read to be stored in fh.
+// Use this to see the file metadata when reading with ReadFile or ReadFileFor.
+// fh is set before any records are read.
+func WithFileHeader(fh *FileHeader) ReadOption {
+	return func(rc *readConfig) {
+		rc.header = fh
+	}
+}
+
+// codec builds a codec for reading data written with the writer schema into
+// typ, using schema resolution if requested.
+func (rc *readConfig) codec(writer Schema, typ reflect.Type) (Codec, error) {
+	if !rc.resolve {
+		return buildCodec(writer, typ, false)
+	}
+	reader := rc.reader
+	if reader.Type == "" {
+		var err error
+		if reader, err = schemaForType(typ); err != nil {
+			return nil, fmt.Errorf("building reader schema: %w", err)
+		}
+	}
+	return buildResolvedCodec(writer, reader, typ, false)
 }
 
 // ReadFile reads from an AVRO file. The records in the file are decoded into
@@ -159,11 +228,24 @@ func ReadFileFor[T any](r Reader, cb func(val *T, rb *ResourceBank) error) error
 //	}); err != nil {
 //	       return err
 //	}
-func ReadFile(r Reader, out any, cb func(val unsafe.Pointer, rb *ResourceBank) error) error {
+//
+// By default the schema in the file must match the type of out. Use
+// WithReaderSchema or WithSchemaResolution to resolve differences between the
+// schema the file was written with and the schema for out. Use WithParallelism
+// to decode the file on several goroutines.
+func ReadFile(r Reader, out any, cb func(val unsafe.Pointer, rb *ResourceBank) error, opts ...ReadOption) error {
+	var rc readConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+
 	fh, err := readFileHeader(r)
 	if err != nil {
 		return err
 	}
+	if rc.header != nil {
+		*rc.header = fh
+	}
 
 	decoder, err := fh.decoder()
 	if err != nil {
@@ -175,21 +257,32 @@ func ReadFile(r Reader, out any, cb func(val unsafe.Pointer, rb *ResourceBank) e
 		return err
 	}
 
-	codec, err := schema.Codec(out)
+	typ := reflect.TypeOf(out)
+	if typ == nil {
+		return fmt.Errorf("building codec: out must be a struct or pointer to a struct")
+	}
+	isPointer := typ.Kind() == reflect.Pointer
+	if isPointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("building codec: out must be a struct or pointer to a struct")
+	}
+
+	codec, err := rc.codec(schema, typ)
 	if err != nil {
 		return fmt.Errorf("building codec: %w", err)
 	}
 
-	// At this point we know out is either a struct or a pointer to a struct.
-	// We repeat some work from schema.Codec
-	typ := reflect.TypeOf(out)
-	var rtyp, p unsafe.Pointer
+	if rc.workers > 1 {
+		return readFileParallel(r, fh, codec, typ, cb, &rc)
+	}
 
-	if typ.Kind() == reflect.Pointer {
+	var rtyp, p unsafe.Pointer
+	if isPointer {
 		// Pointer to a struct is what we really want. We can write to this as
 		// Go semantics would allow us to write to the underlying struct without
 		// weird unsafe tricks
-		typ = typ.Elem()
 		rtyp = unpackEFace(typ).data
 		p = unpackEFace(out).data
 	} else {
@@ -281,7 +374,36 @@ type block struct {
 
 // readFileBlocks reads blocks from an AVRO file, yielding each block's data
 // and count of records.
-func readFileBlocks(r Reader, decoder compressionCodec, hdrSig [16]byte) iter.Seq2[block, error] {
+func readFileBlocks(r Reader, decoder CompressionCodec, hdrSig [16]byte) iter.Seq2[block, error] {
+	return decompressBlocks(readRawBlocks(r, hdrSig, true), decoder)
+}
+
+// decompressBlocks decompresses each block from blocks using decoder.
+func decompressBlocks(blocks iter.Seq2[block, error], decoder CompressionCodec) iter.Seq2[block, error] {
+	return func(yield func(block, error) bool) {
+		for b, err := range blocks {
+			if err != nil {
+				yield(block{}, err)
+				return
+			}
+
+			uncompressed, err := decoder.Decompress(b.data)
+			if err != nil {
+				yield(block{}, fmt.Errorf("decompress failed: %w", err))
+				return
+			}
+
+			if !yield(block{data: uncompressed, count: b.count}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readRawBlocks reads blocks from an AVRO file without decompressing them. If
+// reuse is true the buffer for the block data is re-used for each block, so
+// the data is only valid until the next block is read.
+func readRawBlocks(r Reader, hdrSig [16]byte, reuse bool) iter.Seq2[block, error] {
 	return func(yield func(block, error) bool) {
 		var compressed []byte
 		for {
@@ -293,15 +415,23 @@ func readFileBlocks(r Reader, decoder compressionCodec, hdrSig [16]byte) iter.Se
 				yield(block{}, fmt.Errorf("reading item count. %w", err))
 				return
 			}
+			if count < 0 {
+				yield(block{}, fmt.Errorf("negative block item count %d", count))
+				return
+			}
 			dataLength, err := binary.ReadVarint(r)
 			if err != nil {
 				yield(block{}, fmt.Errorf("reading data block length. %w", err))
 				return
 			}
-			if cap(compressed) < int(dataLength) {
-				compressed = make([]byte, dataLength)
-			} else {
+			if dataLength < 0 {
+				yield(block{}, fmt.Errorf("negative data block length %d", dataLength))
+				return
+			}
+			if reuse && cap(compressed) >= int(dataLength) {
 				compressed = compressed[:dataLength]
+			} else {
+				compressed = make([]byte, dataLength)
 			}
 			if n, err := io.ReadFull(r, compressed); err != nil {
 				yield(block{}, fmt.Errorf("reading %d bytes of compressed data: %w after %d bytes", dataLength, err, n))
@@ -320,13 +450,7 @@ func readFileBlocks(r Reader, decoder compressionCodec, hdrSig [16]byte) iter.Se
 				return
 			}
 
-			uncompressed, err := decoder.decompress(compressed)
-			if err != nil {
-				yield(block{}, fmt.Errorf("decompress failed: %w", err))
-				return
-			}
-
-			if !yield(block{data: uncompressed, count: count}, nil) {
+			if !yield(block{data: compressed, count: count}, nil) {
 				return
 			}
 		}
@@ -389,81 +513,3 @@ func readBytes(r Reader) ([]byte, error) {
 	_, err = io.ReadFull(r, v)
 	return v, err
 }
-
-type compressionCodec interface {
-	decompress(compressed []byte) ([]byte, error)
-	compress(uncompressed []byte) ([]byte, error)
-}
-
-type nullCompression struct{}
-
-func (nullCompression) decompress(compressed []byte) ([]byte, error) {
-	return compressed, nil
-}
-
-func (nullCompression) compress(uncompressed []byte) ([]byte, error) {
-	return uncompressed, nil
-}
-
-type deflate struct {
-	reader io.Reader
-	writer *flate.Writer
-	buf    bytes.Reader
-	out    bytes.Buffer
-}
-
-func (d *deflate) decompress(compressed []byte) ([]byte, error) {
-	d.buf.Reset(compressed)
-	if d.reader == nil {
-		d.reader = flate.NewReader(nil)
-	}
-	d.reader.(flate.Resetter).Reset(&d.buf, nil)
-
-	d.out.Reset()
-	d.out.ReadFrom(d.reader)
-
-	return d.out.Bytes(), nil
-}
-
-func (d *deflate) compress(uncompressed []byte) ([]byte, error) {
-	d.out.Reset()
-	if d.writer == nil {
-		d.writer, _ = flate.NewWriter(&d.out, flate.DefaultCompression)
-	}
-	d.writer.Reset(&d.out)
-	if _, err := d.writer.Write(uncompressed); err != nil {
-		return nil, fmt.Errorf("writing to deflate compressor: %w", err)
-	}
-	if err := d.writer.Close(); err != nil {
-		return nil, fmt.Errorf("flushing deflate compressor: %w", err)
-	}
-
-	return d.out.Bytes(), nil
-}
-
-type snappyCodec struct {
-	buf []byte
-}
-
-func (s *snappyCodec) decompress(compressed []byte) ([]byte, error) {
-	var err error
-	s.buf, err = snappy.Decode(s.buf[:cap(s.buf)], compressed[:len(compressed)-4])
-	if err != nil {
-		return nil, fmt.Errorf("snappy decode failed: %w", err)
-	}
-
-	crc := binary.BigEndian.Uint32(compressed[len(compressed)-4:])
-	if crc32.ChecksumIEEE(s.buf) != crc {
-		return nil, errors.New("snappy checksum mismatch")
-	}
-
-	return s.buf, nil
-}
-
-func (s *snappyCodec) compress(uncompressed []byte) ([]byte, error) {
-	s.buf = snappy.Encode(s.buf[:cap(s.buf)], uncompressed)
-	crc := crc32.ChecksumIEEE(uncompressed)
-	s.buf = binary.BigEndian.AppendUint32(s.buf, crc)
-
-	return s.buf, nil
-}
@@ -0,0 +1,116 @@
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+)
+
+// NewFileRangeReader returns a FileReader that reads some of the blocks of an
+// AVRO file. It reads the blocks that start in the byte range [start, end) of
+// the file. Each block in an AVRO file is preceded by a sync marker (the
+// header ends with one), and we consider a block to start where its sync
+// marker starts. The reader finds the first sync marker at or after start, and
+// reads blocks until it reaches one that starts at or beyond end.
+//
+// This means a large file can be split into byte ranges that are read
+// independently, perhaps by different goroutines or processes, and each block
+// is read by exactly one of the readers. The ranges don't need to line up with
+// block boundaries, but together they must cover the whole file without
+// overlapping.
+//
+//	size := fileInfo.Size()
+//	for i := range n {
+//	    fr, err := avro.NewFileRangeReader[myrecord](f, size*i/n, size*(i+1)/n)
+//	    ...
+//	}
+func NewFileRangeReader[T any](r io.ReaderAt, start, end int64, opts ...ReadOption) (*FileReader[T], error) {
+	hr := newCountingReader(r, 0)
+	fh, err := readFileHeader(hr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header ends with the sync marker.
+	syncPos := max(start, hr.offset-int64(len(fh.Sync)))
+	syncPos, err = findSync(r, fh.Sync, syncPos, end)
+	if err != nil {
+		return nil, fmt.Errorf("finding first sync marker: %w", err)
+	}
+
+	return newFileReader[T](fh, readRangeBlocks(r, fh.Sync, syncPos, end), opts)
+}
+
+// readRangeBlocks reads raw blocks from an AVRO file starting with the block
+// after the sync marker at syncPos, and continuing until the sync marker
+// preceding a block is at or beyond end. If syncPos is at or beyond end it
+// reads nothing.
+func readRangeBlocks(r io.ReaderAt, sync [16]byte, syncPos, end int64) iter.Seq2[block, error] {
+	return func(yield func(block, error) bool) {
+		if syncPos >= end {
+			return
+		}
+		cr := newCountingReader(r, syncPos+int64(len(sync)))
+		for b, err := range readRawBlocks(cr, sync, true) {
+			if !yield(b, err) || err != nil {
+				return
+			}
+			// The block ends with the sync marker for the next block.
+			if cr.offset-int64(len(sync)) >= end {
+				return
+			}
+		}
+	}
+}
+
+// findSync returns the position of the first sync marker in r that starts at
+// or after start. If there's no sync marker before end it returns end.
+func findSync(r io.ReaderAt, sync [16]byte, start, end int64) (int64, error) {
+	const chunkSize = 64 * 1024
+	buf := make([]byte, chunkSize+len(sync)-1)
+	for pos := start; pos < end; pos += chunkSize {
+		n, err := r.ReadAt(buf, pos)
+		if i := bytes.Index(buf[:n], sync[:]); i >= 0 {
+			return min(pos+int64(i), end), nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+	}
+	return end, nil
+}
+
+// countingReader is a buffered Reader that reads from an io.ReaderAt, and
+// keeps track of how far through the file it is.
+type countingReader struct {
+	br     *bufio.Reader
+	offset int64
+}
+
+func newCountingReader(r io.ReaderAt, offset int64) *countingReader {
+	return &countingReader{
+		br:     bufio.NewReader(io.NewSectionReader(r, offset, math.MaxInt64-offset)),
+		offset: offset,
+	}
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.br.Read(p)
+	c.offset += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err == nil {
+		c.offset++
+	}
+	return b, err
+}
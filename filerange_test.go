@@ -0,0 +1,75 @@
+package avro_test
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+func TestFileRangeReader(t *testing.T) {
+	data, exp := writeParallelFile(t, 1000)
+	r := bytes.NewReader(data)
+	size := int64(len(data))
+
+	for _, n := range []int64{1, 2, 3, 7, 50, size / 10, size} {
+		t.Run(strconv.Itoa(int(n)), func(t *testing.T) {
+			var got []parallelRecord
+			for i := range n {
+				fr, err := avro.NewFileRangeReader[parallelRecord](r, size*i/n, size*(i+1)/n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for v, err := range fr.All() {
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, *v)
+				}
+			}
+			if diff := cmp.Diff(exp, got); diff != "" {
+				t.Fatalf("records not as expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileRangeReaderBlocks(t *testing.T) {
+	data, _ := writeParallelFile(t, 1000)
+	r := bytes.NewReader(data)
+	size := int64(len(data))
+
+	// Splitting the file in two should give each reader some of the blocks.
+	var counts [2]int
+	for i := range 2 {
+		fr, err := avro.NewFileRangeReader[parallelRecord](r, size*int64(i)/2, size*int64(i+1)/2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range fr.Blocks() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[i]++
+		}
+	}
+	if counts[0] == 0 || counts[1] == 0 {
+		t.Fatalf("blocks not split between readers: %v", counts)
+	}
+
+	// A range beyond the end of the file has no blocks
+	fr, err := avro.NewFileRangeReader[parallelRecord](r, size, size+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range fr.All() {
+		t.Fatal("expected no records")
+	}
+
+	// A range that's not a file fails
+	if _, err := avro.NewFileRangeReader[parallelRecord](bytes.NewReader([]byte("not a file")), 0, 10); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,206 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+)
+
+// FileReader reads records of type T from an AVRO file. Unlike ReadFile it
+// gives access to the file header, and the application pulls records from the
+// reader rather than having them pushed to a callback. It is not safe for
+// concurrent use.
+//
+//	fr, err := avro.NewFileReader[myrecord](f)
+//	if err != nil {
+//	    return err
+//	}
+//	defer fr.Close()
+//	for val, err := range fr.All() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    records = append(records, *val)
+//	}
+type FileReader[T any] struct {
+	header FileHeader
+	schema Schema
+	codec  Codec
+	typ    reflect.Type
+
+	nextBlock func() (block, error, bool)
+	stop      func()
+
+	br ReadBuf
+	// remaining is the number of records left to read in the current block
+	remaining int64
+	// err is set once we can't read any further
+	err error
+}
+
+// Block is a block of records from an AVRO file.
+type Block struct {
+	// Count is the number of records in the block.
+	Count int64
+	// Data is the AVRO encoded records, after decompression.
+	Data []byte
+}
+
+// NewFileReader reads the header from an AVRO file in r and returns a
+// FileReader for reading records of type T from the rest of the file. As with
+// ReadFile the schema in the file must match T unless WithReaderSchema or
+// WithSchemaResolution is passed.
+func NewFileReader[T any](r Reader, opts ...ReadOption) (*FileReader[T], error) {
+	fh, err := readFileHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return newFileReader[T](fh, readRawBlocks(r, fh.Sync, true), opts)
+}
+
+// newFileReader returns a FileReader that reads records from the raw
+// (compressed) blocks in blocks.
+func newFileReader[T any](fh FileHeader, blocks iter.Seq2[block, error], opts []ReadOption) (*FileReader[T], error) {
+	var rc readConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+	if rc.header != nil {
+		*rc.header = fh
+	}
+
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	decoder, err := fh.decoder()
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := fh.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := rc.codec(schema, typ)
+	if err != nil {
+		return nil, fmt.Errorf("building codec: %w", err)
+	}
+
+	f := &FileReader[T]{
+		header: fh,
+		schema: schema,
+		codec:  codec,
+		typ:    typ,
+	}
+	f.nextBlock, f.stop = iter.Pull2(decompressBlocks(blocks, decoder))
+	return f, nil
+}
+
+// Header returns the file header. This includes the file metadata.
+func (f *FileReader[T]) Header() FileHeader {
+	return f.header
+}
+
+// Schema returns the schema the file was written with.
+func (f *FileReader[T]) Schema() Schema {
+	return f.schema
+}
+
+// Next returns the next record in the file. It returns io.EOF when there are no
+// more records. Each record is newly allocated, and the application may keep
+// it. If a record can't be read Next returns the error, and will return it
+// again on every subsequent call.
+func (f *FileReader[T]) Next() (*T, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for f.remaining == 0 {
+		if _, err := f.NextBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The record itself is allocated on its own so the application can keep
+	// it without keeping anything else. Its contents, such as strings, are
+	// allocated from the bank for the current block.
+	p := reflect.New(f.typ).UnsafePointer()
+	if err := f.codec.Read(&f.br, p); err != nil {
+		f.err = fmt.Errorf("failed to read item in file. %w", err)
+		return nil, f.err
+	}
+	f.remaining--
+	return (*T)(p), nil
+}
+
+// All returns an iterator over the remaining records in the file. Iteration
+// stops after the first error.
+func (f *FileReader[T]) All() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for {
+			v, err := f.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// NextBlock moves to the next block of the file and returns it. Any records not
+// yet read from the current block are skipped. Subsequent calls to Next return
+// records from the new block. The block data is only valid until the next call
+// to Next or NextBlock. NextBlock returns io.EOF at the end of the file.
+func (f *FileReader[T]) NextBlock() (Block, error) {
+	if f.err != nil {
+		return Block{}, f.err
+	}
+	b, err, ok := f.nextBlock()
+	if !ok {
+		f.err = io.EOF
+		return Block{}, f.err
+	}
+	if err != nil {
+		f.err = err
+		return Block{}, err
+	}
+	// Records from the previous block may still be in use, so rather than
+	// reuse their bank we start a new one. We don't Close the old bank: the
+	// GC frees it once the application drops the records.
+	f.br.ExtractResourceBank()
+	f.br.Reset(b.data)
+	f.remaining = b.count
+	return Block{Count: b.count, Data: b.data}, nil
+}
+
+// Blocks returns an iterator over the remaining blocks in the file. Iteration
+// stops after the first error.
+func (f *FileReader[T]) Blocks() iter.Seq2[Block, error] {
+	return func(yield func(Block, error) bool) {
+		for {
+			b, err := f.NextBlock()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(b, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close stops reading from the file. It does not close the underlying reader.
+// Next and NextBlock return io.EOF after Close is called.
+func (f *FileReader[T]) Close() error {
+	f.stop()
+	if f.err == nil {
+		f.err = io.EOF
+	}
+	return nil
+}
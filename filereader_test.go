@@ -0,0 +1,224 @@
+package avro_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+type readerRecord struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func writeReaderFile(t *testing.T, count int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	// A tiny block size means we get a block per record
+	enc, err := avro.NewEncoderFor[readerRecord](&buf, avro.CompressionDeflate, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range count {
+		if err := enc.Encode(&readerRecord{ID: int64(i), Name: "name"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFileReader(t *testing.T) {
+	data := writeReaderFile(t, 3)
+
+	fr, err := avro.NewFileReader[readerRecord](bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Close()
+
+	if codec := string(fr.Header().Meta["avro.codec"]); codec != "deflate" {
+		t.Errorf("codec in header is %q", codec)
+	}
+	schema, err := avro.SchemaForType(readerRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(schema, fr.Schema()); diff != "" {
+		t.Errorf("schema not as expected (-want +got):\n%s", diff)
+	}
+
+	var got []*readerRecord
+	for v, err := range fr.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	exp := []*readerRecord{
+		{ID: 0, Name: "name"},
+		{ID: 1, Name: "name"},
+		{ID: 2, Name: "name"},
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("records not as expected (-want +got):\n%s", diff)
+	}
+
+	if _, err := fr.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestFileReaderBlocks(t *testing.T) {
+	data := writeReaderFile(t, 3)
+
+	fr, err := avro.NewFileReader[readerRecord](bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fr.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Count != 1 {
+		t.Fatalf("block count %d, want 1", b.Count)
+	}
+	var first readerRecord
+	if err := avro.Unmarshal(fr.Schema(), b.Data, &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != 0 {
+		t.Fatalf("first record has ID %d", first.ID)
+	}
+
+	// Skip the rest of the first block and the whole of the second. Records
+	// are then read from the third block.
+	if _, err := fr.NextBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for b, err := range fr.Blocks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Count != 1 {
+			t.Fatalf("block count %d, want 1", b.Count)
+		}
+		break
+	}
+	v, err := fr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 2 {
+		t.Fatalf("record has ID %d, want 2", v.ID)
+	}
+
+	if _, err := fr.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestFileReaderClose(t *testing.T) {
+	data := writeReaderFile(t, 3)
+
+	fr, err := avro.NewFileReader[readerRecord](bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := fr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 0 {
+		t.Fatalf("first record has ID %d", v.ID)
+	}
+	if err := fr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fr.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF after close, got %v", err)
+	}
+}
+
+func TestFileReaderAllocs(t *testing.T) {
+	// Each record the application keeps should cost one allocation for the
+	// record itself, plus a share of the allocations for its contents.
+	// Allocating each record in its own ResourceBank costs several
+	// allocations and many times the size of the record.
+	const count = 10_000
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[readerRecord](&buf, avro.CompressionNull, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range count {
+		if err := enc.Encode(&readerRecord{ID: int64(i), Name: "name " + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	fr, err := avro.NewFileReader[readerRecord](bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Close()
+
+	var kept []*readerRecord
+	allocs := testing.AllocsPerRun(count/2, func() {
+		v, err := fr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept = append(kept[:0], v)
+	})
+	if allocs > 1.5 {
+		t.Fatalf("%.2f allocations per record", allocs)
+	}
+}
+
+func TestFileReaderErrors(t *testing.T) {
+	data := writeReaderFile(t, 2)
+
+	if _, err := avro.NewFileReader[readerRecord](bytes.NewReader(data[:10])); err == nil {
+		t.Fatal("expected an error for a truncated header")
+	}
+
+	type other struct {
+		ID string `json:"id"`
+	}
+	if _, err := avro.NewFileReader[other](bytes.NewReader(data)); err == nil {
+		t.Fatal("expected an error for a mismatched type")
+	}
+
+	// Truncate the last block
+	fr, err := avro.NewFileReader[readerRecord](bytes.NewReader(data[:len(data)-5]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	var lastErr error
+	for _, err := range fr.All() {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		n++
+	}
+	if n != 1 || lastErr == nil {
+		t.Fatalf("read %d records with error %v", n, lastErr)
+	}
+	if _, err := fr.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("expected the error to be repeated, got %v", err)
+	}
+}
@@ -5,16 +5,73 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 )
 
 type Compression string
 
 const (
-	CompressionNull    Compression = "null"
-	CompressionDeflate Compression = "deflate"
-	CompressionSnappy  Compression = "snappy"
+	CompressionNull      Compression = "null"
+	CompressionDeflate   Compression = "deflate"
+	CompressionSnappy    Compression = "snappy"
+	CompressionZstandard Compression = "zstandard"
+	CompressionBzip2     Compression = "bzip2"
+	CompressionXZ        Compression = "xz"
 )
 
+// WriteOption is an option for writing AVRO files, used by NewFileWriter,
+// NewEncoderFor and others.
+type WriteOption func(*writeConfig)
+
+type writeConfig struct {
+	level int
+	meta  map[string][]byte
+
+	maxBlockRows  int
+	flushInterval time.Duration
+	closeWriter   bool
+
+	maxFileBytes int64
+	maxFileRows  int64
+
+	workers int
+}
+
+func newWriteConfig(opts []WriteOption) writeConfig {
+	wc := writeConfig{level: DefaultCompressionLevel}
+	for _, opt := range opts {
+		opt(&wc)
+	}
+	return wc
+}
+
+// WithCompressionLevel sets the compression level. The range of levels depends
+// on the compression codec: -2 to 9 for deflate, 1 to 22 for zstandard, 1 to 9
+// for bzip2 and 0 to 9 for xz. Higher levels compress better but more slowly.
+// The level is ignored by null and snappy compression, and is passed to the
+// CompressionFactory of codecs added with RegisterCompression.
+func WithCompressionLevel(level int) WriteOption {
+	return func(wc *writeConfig) {
+		wc.level = level
+	}
+}
+
+// WithMetadata adds an entry to the metadata in the file header. Keys starting
+// with "avro." are reserved by the AVRO specification, and the writer returns an
+// error if you try to set one. Metadata can't be added when appending to an
+// existing file, as the header has already been written.
+func WithMetadata(key string, value []byte) WriteOption {
+	return func(wc *writeConfig) {
+		if wc.meta == nil {
+			wc.meta = make(map[string][]byte)
+		}
+		wc.meta[key] = value
+	}
+}
+
 // FileWriter provides limited support for writing AVRO files. It allows you to
 // write blocks of already encoded data. Actually encoding data as AVRO is supported
 // by the Encoder type.
@@ -24,37 +81,70 @@ type FileWriter struct {
 	// that until we have encoding support.
 	schema      []byte
 	compression Compression
+	meta        map[string][]byte
 	varintBuf   [binary.MaxVarintLen64]byte
-	compressor  compressionCodec
+	compressor  CompressionCodec
 }
 
 // NewFileWriter creates a new FileWriter. The schema is the JSON encoded
 // schema. The compression parameter indicates the compression codec to use.
-func NewFileWriter(schema []byte, compression Compression) (*FileWriter, error) {
+func NewFileWriter(schema []byte, compression Compression, opts ...WriteOption) (*FileWriter, error) {
+	wc := newWriteConfig(opts)
+	for key := range wc.meta {
+		if strings.HasPrefix(key, reservedMetaPrefix) {
+			return nil, fmt.Errorf("metadata key %q is reserved", key)
+		}
+	}
+
 	// Generate a random sync value
 	f := &FileWriter{
 		schema:      schema,
 		compression: compression,
+		meta:        wc.meta,
 	}
 	_, err := rand.Read(f.sync[:])
 	if err != nil {
 		return nil, fmt.Errorf("creating sync value: %w", err)
 	}
 
-	switch compression {
-	case CompressionNull:
-		f.compressor = nullCompression{}
-	case CompressionDeflate:
-		f.compressor = &deflate{}
-	case CompressionSnappy:
-		f.compressor = &snappyCodec{}
-	default:
-		return nil, fmt.Errorf("compression codec %s not supported", compression)
+	if f.compressor, err = newCompressionCodec(compression, wc.level); err != nil {
+		return nil, err
 	}
 
 	return f, nil
 }
 
+// NewFileWriterForHeader creates a FileWriter for adding blocks to an existing
+// AVRO file with the given header. The FileWriter uses the schema, compression
+// codec and sync marker from the header. Don't call WriteHeader on the
+// FileWriter: just write blocks at the end of the existing file.
+func NewFileWriterForHeader(fh FileHeader, opts ...WriteOption) (*FileWriter, error) {
+	wc := newWriteConfig(opts)
+	if len(wc.meta) > 0 {
+		return nil, fmt.Errorf("can't add metadata to an existing file")
+	}
+
+	schema, ok := fh.Meta["avro.schema"]
+	if !ok {
+		return nil, fmt.Errorf("no schema found in file header")
+	}
+	compression := CompressionNull
+	if codec, ok := fh.Meta["avro.codec"]; ok {
+		compression = Compression(codec)
+	}
+
+	f := &FileWriter{
+		sync:        fh.Sync,
+		schema:      schema,
+		compression: compression,
+	}
+	var err error
+	if f.compressor, err = newCompressionCodec(compression, wc.level); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
 // WriteHeader writes the AVRO file header to the writer.
 func (f *FileWriter) WriteHeader(w io.Writer) error {
 	buf := make([]byte, 0, 1024)
@@ -69,16 +159,20 @@ func (f *FileWriter) AppendHeader(buf []byte) []byte {
 	buf = append(buf, FileMagic[:]...)
 
 	// Count of how many metadata blocks there are.
-	buf = binary.AppendVarint(buf, 2)
+	buf = binary.AppendVarint(buf, int64(2+len(f.meta)))
 
 	// Write the metadata block. There will be an entry for the compression type
-	// and an entry for the schema. Each entry is a string key followed by a
-	// string value. Strings are written as a varint encoded length and then the
-	// bytes of the string.
+	// and an entry for the schema, followed by any user metadata in key order.
+	// Each entry is a string key followed by a string value. Strings are written
+	// as a varint encoded length and then the bytes of the string.
 	buf = appendString(buf, "avro.schema")
 	buf = appendString(buf, f.schema)
 	buf = appendString(buf, "avro.codec")
 	buf = appendString(buf, f.compression)
+	for _, key := range slices.Sorted(maps.Keys(f.meta)) {
+		buf = appendString(buf, key)
+		buf = appendString(buf, f.meta[key])
+	}
 
 	// Append a zero count to indicate no more header blocks.
 	buf = binary.AppendVarint(buf, 0)
@@ -107,16 +201,28 @@ func (f *FileWriter) writeVarInt(w io.Writer, v int) error {
 // WriteBlock writes a block of data to the writer. The block must be rowCount
 // rows of AVRO encoded data.
 func (f *FileWriter) WriteBlock(w io.Writer, rowCount int, block []byte) error {
+	_, err := f.writeBlock(w, rowCount, block)
+	return err
+}
+
+// writeBlock writes a block of data to the writer and returns the length of
+// the block after compression.
+func (f *FileWriter) writeBlock(w io.Writer, rowCount int, block []byte) (int, error) {
+	compressed, err := f.compressor.Compress(block)
+	if err != nil {
+		return 0, fmt.Errorf("compressing block: %w", err)
+	}
+	return len(compressed), f.writeCompressedBlock(w, rowCount, compressed)
+}
+
+// writeCompressedBlock writes a block of data that has already been compressed
+// to the writer.
+func (f *FileWriter) writeCompressedBlock(w io.Writer, rowCount int, compressed []byte) error {
 	// Write the count of rows in the block
 	if err := f.writeVarInt(w, rowCount); err != nil {
 		return fmt.Errorf("writing row count: %w", err)
 	}
 
-	compressed, err := f.compressor.compress(block)
-	if err != nil {
-		return fmt.Errorf("compressing block: %w", err)
-	}
-
 	// Write the (compressed) block size
 	if err := f.writeVarInt(w, len(compressed)); err != nil {
 		return fmt.Errorf("writing block len: %w", err)
@@ -2,6 +2,7 @@ package avro_test
 
 import (
 	"bufio"
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -51,7 +52,7 @@ func TestWritingFile(t *testing.T) {
 		6, 'h', 'a', 't',
 	}
 
-	for _, compression := range []avro.Compression{avro.CompressionDeflate, avro.CompressionSnappy} {
+	for _, compression := range []avro.Compression{avro.CompressionDeflate, avro.CompressionSnappy, avro.CompressionZstandard, avro.CompressionBzip2, avro.CompressionXZ} {
 		t.Run(string(compression), func(t *testing.T) {
 			dir := t.TempDir()
 			filename := filepath.Join(dir, "test.avro")
@@ -105,3 +106,59 @@ func TestWritingFile(t *testing.T) {
 		})
 	}
 }
+
+func TestFileMetadata(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[record](&buf, avro.CompressionNull, 1000,
+		avro.WithMetadata("producer.version", []byte("1.2.3")),
+		avro.WithMetadata("row.count", []byte("1")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&record{Name: "jim"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var fh avro.FileHeader
+	var actual []record
+	if err := avro.ReadFileFor(bytes.NewReader(buf.Bytes()), func(val *record, rb *avro.ResourceBank) error {
+		actual = append(actual, *val)
+		return nil
+	}, avro.WithFileHeader(&fh)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]record{{Name: "jim"}}, actual); diff != "" {
+		t.Fatalf("records not as expected. %s", diff)
+	}
+
+	exp := map[string][]byte{
+		"producer.version": []byte("1.2.3"),
+		"row.count":        []byte("1"),
+	}
+	if diff := cmp.Diff(exp, fh.UserMeta()); diff != "" {
+		t.Fatalf("metadata not as expected. %s", diff)
+	}
+	if codec := string(fh.Meta["avro.codec"]); codec != "null" {
+		t.Fatalf("codec is %q", codec)
+	}
+
+	if _, err := avro.NewFileWriterForHeader(fh, avro.WithMetadata("row.count", []byte("2"))); err == nil {
+		t.Fatal("expected an error adding metadata to an existing file")
+	}
+}
+
+func TestFileMetadataReserved(t *testing.T) {
+	for _, key := range []string{"avro.schema", "avro.codec", "avro.other"} {
+		if _, err := avro.NewFileWriter([]byte(`"int"`), avro.CompressionNull, avro.WithMetadata(key, []byte("x"))); err == nil {
+			t.Errorf("expected an error setting %s", key)
+		}
+	}
+}
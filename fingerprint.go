@@ -0,0 +1,195 @@
+package avro
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// CanonicalForm returns the Parsing Canonical Form of the schema, as defined in
+// the AVRO specification. Two schemas with the same canonical form describe the
+// same data, so the canonical form (or a fingerprint of it) can be used to
+// identify a schema.
+//
+// In the canonical form names are fully qualified, attributes that don't affect
+// how data is read (doc, aliases, defaults, logical types and other properties)
+// are removed, and named types are written in full only the first time they
+// appear.
+func (s Schema) CanonicalForm() ([]byte, error) {
+	resolved, err := resolveNames(s)
+	if err != nil {
+		return nil, fmt.Errorf("resolving names: %w", err)
+	}
+	c := canonicalWriter{defined: make(map[string]bool)}
+	if err := c.write(resolved); err != nil {
+		return nil, err
+	}
+	return c.buf, nil
+}
+
+// Fingerprint returns the CRC-64-AVRO (Rabin) fingerprint of the schema's
+// Parsing Canonical Form. This is the fingerprint used by AVRO single-object
+// encoding.
+func (s Schema) Fingerprint() (uint64, error) {
+	canon, err := s.CanonicalForm()
+	if err != nil {
+		return 0, err
+	}
+	return crc64Avro(canon), nil
+}
+
+// FingerprintMD5 returns the MD5 hash of the schema's Parsing Canonical Form.
+func (s Schema) FingerprintMD5() ([md5.Size]byte, error) {
+	canon, err := s.CanonicalForm()
+	if err != nil {
+		return [md5.Size]byte{}, err
+	}
+	return md5.Sum(canon), nil
+}
+
+// FingerprintSHA256 returns the SHA-256 hash of the schema's Parsing Canonical
+// Form.
+func (s Schema) FingerprintSHA256() ([sha256.Size]byte, error) {
+	canon, err := s.CanonicalForm()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(canon), nil
+}
+
+// canonicalWriter writes the Parsing Canonical Form of a schema whose names
+// have been resolved.
+type canonicalWriter struct {
+	buf []byte
+	// defined records the full names of named types we've already written.
+	defined map[string]bool
+}
+
+func (c *canonicalWriter) write(s Schema) error {
+	switch s.Type {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		// Any logical type or other attribute is dropped, leaving just the
+		// name of the primitive type.
+		return c.writeString(s.Type)
+
+	case "union":
+		c.buf = append(c.buf, '[')
+		for i, u := range s.Union {
+			if i > 0 {
+				c.buf = append(c.buf, ',')
+			}
+			if err := c.write(u); err != nil {
+				return err
+			}
+		}
+		c.buf = append(c.buf, ']')
+		return nil
+
+	case "array":
+		c.buf = append(c.buf, `{"type":"array","items":`...)
+		if err := c.write(s.Object.Items); err != nil {
+			return err
+		}
+		c.buf = append(c.buf, '}')
+		return nil
+
+	case "map":
+		c.buf = append(c.buf, `{"type":"map","values":`...)
+		if err := c.write(s.Object.Values); err != nil {
+			return err
+		}
+		c.buf = append(c.buf, '}')
+		return nil
+
+	case "record", "enum", "fixed":
+		name := s.Object.FullName()
+		if c.defined[name] {
+			return c.writeString(name)
+		}
+		c.defined[name] = true
+
+		c.buf = append(c.buf, `{"name":`...)
+		if err := c.writeString(name); err != nil {
+			return err
+		}
+		c.buf = append(c.buf, `,"type":`...)
+		if err := c.writeString(s.Type); err != nil {
+			return err
+		}
+
+		switch s.Type {
+		case "record":
+			c.buf = append(c.buf, `,"fields":[`...)
+			for i, f := range s.Object.Fields {
+				if i > 0 {
+					c.buf = append(c.buf, ',')
+				}
+				c.buf = append(c.buf, `{"name":`...)
+				if err := c.writeString(f.Name); err != nil {
+					return err
+				}
+				c.buf = append(c.buf, `,"type":`...)
+				if err := c.write(f.Type); err != nil {
+					return fmt.Errorf("field %q: %w", f.Name, err)
+				}
+				c.buf = append(c.buf, '}')
+			}
+			c.buf = append(c.buf, ']')
+		case "enum":
+			c.buf = append(c.buf, `,"symbols":[`...)
+			for i, sym := range s.Object.Symbols {
+				if i > 0 {
+					c.buf = append(c.buf, ',')
+				}
+				if err := c.writeString(sym); err != nil {
+					return err
+				}
+			}
+			c.buf = append(c.buf, ']')
+		case "fixed":
+			c.buf = append(c.buf, `,"size":`...)
+			c.buf = strconv.AppendInt(c.buf, int64(s.Object.Size), 10)
+		}
+		c.buf = append(c.buf, '}')
+		return nil
+	}
+
+	return fmt.Errorf("unexpected schema type %q", s.Type)
+}
+
+func (c *canonicalWriter) writeString(s string) error {
+	var err error
+	c.buf, err = jsontext.AppendQuote(c.buf, s)
+	if err != nil {
+		return fmt.Errorf("quoting %q: %w", s, err)
+	}
+	return nil
+}
+
+// crc64Empty is the CRC-64-AVRO fingerprint of an empty input. It's also the
+// polynomial used to build the lookup table.
+const crc64Empty = 0xc15d213aa4d7a795
+
+var crc64Table = func() (table [256]uint64) {
+	for i := range table {
+		fp := uint64(i)
+		for range 8 {
+			fp = (fp >> 1) ^ (crc64Empty & -(fp & 1))
+		}
+		table[i] = fp
+	}
+	return table
+}()
+
+// crc64Avro calculates the CRC-64-AVRO fingerprint of data, following the
+// algorithm in the AVRO specification.
+func crc64Avro(data []byte) uint64 {
+	fp := uint64(crc64Empty)
+	for _, b := range data {
+		fp = (fp >> 8) ^ crc64Table[byte(fp)^b]
+	}
+	return fp
+}
@@ -0,0 +1,185 @@
+package avro
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCanonicalForm(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name:   "primitive",
+			schema: `"int"`,
+			want:   `"int"`,
+		},
+		{
+			name:   "primitive object",
+			schema: `{"type":"long","logicalType":"timestamp-micros"}`,
+			want:   `"long"`,
+		},
+		{
+			name:   "union",
+			schema: `["null", {"type": "string"}]`,
+			want:   `["null","string"]`,
+		},
+		{
+			name:   "array",
+			schema: `{"items": "int", "type": "array", "doc": "ignored"}`,
+			want:   `{"type":"array","items":"int"}`,
+		},
+		{
+			name:   "map",
+			schema: `{"values": {"type": "array", "items": "bytes"}, "type": "map"}`,
+			want:   `{"type":"map","values":{"type":"array","items":"bytes"}}`,
+		},
+		{
+			name:   "fixed",
+			schema: `{"size": 24, "namespace": "org.foo", "type": "fixed", "name": "Foo", "aliases": ["Bar"]}`,
+			want:   `{"name":"org.foo.Foo","type":"fixed","size":24}`,
+		},
+		{
+			name:   "enum",
+			schema: `{"type": "enum", "name": "a.b.Suit", "symbols": ["SPADES", "HEARTS"], "default": "SPADES", "doc": "cards"}`,
+			want:   `{"name":"a.b.Suit","type":"enum","symbols":["SPADES","HEARTS"]}`,
+		},
+		{
+			name: "record",
+			schema: `{
+				"type": "record",
+				"name": "Test",
+				"namespace": "x.y",
+				"doc": "a test",
+				"sqlType": "JSON",
+				"fields": [
+					{"name": "a", "type": "long", "default": 1, "doc": "field a", "order": "descending"},
+					{"name": "b", "type": {"type": "enum", "name": "E", "symbols": ["A", "B"]}},
+					{"name": "c", "type": "E"},
+					{"name": "d", "type": {"type": "fixed", "name": "other.F", "size": 2}},
+					{"name": "e", "type": ["null", "other.F"]}
+				]
+			}`,
+			want: `{"name":"x.y.Test","type":"record","fields":[` +
+				`{"name":"a","type":"long"},` +
+				`{"name":"b","type":{"name":"x.y.E","type":"enum","symbols":["A","B"]}},` +
+				`{"name":"c","type":"x.y.E"},` +
+				`{"name":"d","type":{"name":"other.F","type":"fixed","size":2}},` +
+				`{"name":"e","type":["null","other.F"]}]}`,
+		},
+		{
+			name:   "recursive",
+			schema: `{"type": "record", "name": "List", "fields": [{"name": "next", "type": ["null", "List"]}]}`,
+			want:   `{"name":"List","type":"record","fields":[{"name":"next","type":["null","List"]}]}`,
+		},
+		{
+			name:   "strings",
+			schema: `{"type": "enum", "name": "E", "symbols": ["café"]}`,
+			want:   `{"name":"E","type":"enum","symbols":["café"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := SchemaFromString(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.CanonicalForm()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Fatalf("canonical form not as expected (-want +got):\n%s", diff)
+			}
+
+			// The canonical form is itself a schema, and is its own canonical
+			// form.
+			s, err = SchemaFromString(string(got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := s.CanonicalForm()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(again)); diff != "" {
+				t.Fatalf("canonical form of canonical form differs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCanonicalFormBadReference(t *testing.T) {
+	s := Schema{Type: "array", Object: &SchemaObject{Items: Schema{Type: "Missing"}}}
+	if _, err := s.CanonicalForm(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	// These fingerprints are from the AVRO specification's test suite
+	tests := []struct {
+		schema string
+		want   int64
+	}{
+		{schema: `"null"`, want: 7195948357588979594},
+		{schema: `"boolean"`, want: -6970731678124411036},
+		{schema: `"int"`, want: 8247732601305521295},
+		{schema: `"long"`, want: -3434872931120570953},
+		{schema: `"float"`, want: 5583340709985441680},
+		{schema: `"double"`, want: -8181574048448539266},
+		{schema: `"bytes"`, want: 5746618253357095269},
+		{schema: `"string"`, want: -8142146995180207161},
+		{schema: `{"type": "int", "logicalType": "date"}`, want: 8247732601305521295},
+	}
+
+	for _, test := range tests {
+		t.Run(test.schema, func(t *testing.T) {
+			s, err := SchemaFromString(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Fingerprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(got) != test.want {
+				t.Fatalf("fingerprint %d, want %d", int64(got), test.want)
+			}
+		})
+	}
+}
+
+func TestFingerprintHashes(t *testing.T) {
+	s, err := SchemaFromString(`{"type": "record", "name": "r", "doc": "ignored", "fields": [{"name": "a", "type": "int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canon := []byte(`{"name":"r","type":"record","fields":[{"name":"a","type":"int"}]}`)
+
+	gotMD5, err := s.FingerprintMD5()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotMD5 != md5.Sum(canon) {
+		t.Errorf("MD5 fingerprint %x not as expected", gotMD5)
+	}
+
+	gotSHA, err := s.FingerprintSHA256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotSHA != sha256.Sum256(canon) {
+		t.Errorf("SHA-256 fingerprint %x not as expected", gotSHA)
+	}
+
+	if crc64Avro(nil) != crc64Empty {
+		t.Errorf("fingerprint of empty input should be %x", uint64(crc64Empty))
+	}
+}
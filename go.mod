@@ -5,7 +5,10 @@ go 1.26
 replace github.com/unravelin/null => github.com/unravelin/null/v5 v5.0.1
 
 require (
+	github.com/dsnet/compress v0.0.1
 	github.com/go-json-experiment/json v0.0.0-20260214004413-d219187c3433
 	github.com/golang/snappy v1.0.0
 	github.com/google/go-cmp v0.7.0
+	github.com/klauspost/compress v1.20.1
+	github.com/ulikunitz/xz v0.5.17
 )
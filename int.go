@@ -2,6 +2,7 @@ package avro
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"unsafe"
 )
@@ -9,7 +10,12 @@ import (
 // IntCodec is an avro codec for integers. It supports int64, int32, and int16.
 // We also support uint64, even though the AVRO spec does not specify an
 // unsigned integer type. It is not clear how this will work with BigQuery.
-type IntCodec[T uint64 | int64 | int32 | int16] struct{ omitEmpty bool }
+type IntCodec[T uint64 | int64 | int32 | int16] struct {
+	omitEmpty bool
+	// is32 is set if the schema type is int rather than long. Values outside
+	// the range of a 32 bit integer can't be written.
+	is32 bool
+}
 
 func (IntCodec[T]) Read(r *ReadBuf, p unsafe.Pointer) error {
 	i, err := r.Varint()
@@ -53,7 +59,15 @@ func (rc IntCodec[T]) Omit(p unsafe.Pointer) bool {
 }
 
 func (rc IntCodec[T]) Write(w *WriteBuf, p unsafe.Pointer) {
-	w.Varint(int64(*(*T)(p)))
+	v := *(*T)(p)
+	// Note large uint64 values wrap to negative numbers. We allow that for long
+	// so uint64 values round-trip.
+	i := int64(v)
+	if rc.is32 && ((v > 0 && i < 0) || i < math.MinInt32 || i > math.MaxInt32) {
+		w.SetError(fmt.Errorf("value %d out of range for int", v))
+		return
+	}
+	w.Varint(i)
 }
 
 type (
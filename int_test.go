@@ -383,3 +383,32 @@ func TestInt16RoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestIntCodecWriteRange(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Codec
+		in   any
+		ok   bool
+	}{
+		{name: "int64 in range", c: Int64Codec{is32: true}, in: int64(math.MaxInt32), ok: true},
+		{name: "int64 too big", c: Int64Codec{is32: true}, in: int64(math.MaxInt32 + 1)},
+		{name: "int64 too small", c: Int64Codec{is32: true}, in: int64(math.MinInt32 - 1)},
+		{name: "int64 long", c: Int64Codec{}, in: int64(math.MaxInt32 + 1), ok: true},
+		{name: "uint64 too big", c: Uint64Codec{is32: true}, in: uint64(math.MaxUint64)},
+		{name: "int32", c: Int32Codec{is32: true}, in: int32(math.MinInt32), ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWriteBuf(nil)
+			test.c.Write(w, unpackEFace(test.in).data)
+			if err := w.Err(); (err == nil) != test.ok {
+				t.Fatalf("unexpected error state %v", err)
+			}
+			if !test.ok && w.Len() != 0 {
+				t.Fatalf("data written for out of range value")
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package avro
 
-import "unsafe"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
 
 type eface struct {
 	rtype unsafe.Pointer
@@ -10,3 +15,174 @@ type eface struct {
 func unpackEFace(obj interface{}) *eface {
 	return (*eface)(unsafe.Pointer(&obj))
 }
+
+// interfaceValue returns the dynamic type of the interface of type typ that p
+// points to, and a pointer to the value held in the interface. The type is nil
+// if the interface is nil.
+func interfaceValue(typ reflect.Type, p unsafe.Pointer) (reflect.Type, unsafe.Pointer) {
+	v := reflect.NewAt(typ, p).Elem()
+	if v.IsNil() {
+		return nil, nil
+	}
+	v = v.Elem()
+
+	// Both empty and non-empty interfaces hold a pointer to the value as their
+	// second word, unless the value is itself pointer-shaped, in which case the
+	// value is stored directly.
+	data := &(*eface)(p).data
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Slice:
+		return v.Type(), *data
+	case reflect.Pointer, reflect.Map:
+		return v.Type(), unsafe.Pointer(data)
+	}
+
+	// Structs and arrays may or may not be pointer-shaped, so we take a copy.
+	c := reflect.New(v.Type())
+	c.Elem().Set(v)
+	return v.Type(), c.UnsafePointer()
+}
+
+var anyType = reflect.TypeFor[any]()
+
+// naturalType returns the Go type we decode into when decoding a schema into
+// an interface.
+func naturalType(schema Schema) (reflect.Type, error) {
+	switch schema.Type {
+	case "boolean":
+		return boolType, nil
+	case "int":
+		return int32Type, nil
+	case "long":
+		return int64Type, nil
+	case "float":
+		return floatType, nil
+	case "double":
+		return doubleType, nil
+	case "bytes":
+		return bytesType, nil
+	case "string", "enum":
+		return stringType, nil
+	case "fixed":
+		if schema.Object == nil {
+			return nil, fmt.Errorf("fixed schema does not have object")
+		}
+		return reflect.ArrayOf(schema.Object.Size, reflect.TypeFor[byte]()), nil
+	case "array":
+		return reflect.TypeFor[[]any](), nil
+	case "map", "record":
+		return reflect.TypeFor[map[string]any](), nil
+	case "union":
+		return anyType, nil
+	}
+	return nil, fmt.Errorf("no Go type to represent %s", schema.Type)
+}
+
+// interfaceCodec decodes into an interface value. Values are decoded into the
+// natural Go type for the schema, and that value is stored in the interface.
+// When encoding, the dynamic type of the value held in the interface is used
+// to find a codec.
+type interfaceCodec struct {
+	schema  Schema
+	rtype   reflect.Type
+	natural reflect.Type
+	codec   Codec
+	// codecs caches codecs for the dynamic types we've been asked to write. It
+	// maps reflect.Type to *interfaceWriter
+	codecs sync.Map
+}
+
+type interfaceWriter struct {
+	codec Codec
+	err   error
+}
+
+func (b *codecBuilder) buildInterfaceCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	natural, err := naturalType(schema)
+	if err != nil {
+		return nil, err
+	}
+	// Write codecs use the dynamic type of the value in the interface, so
+	// only need the natural type to implement the interface when reading.
+	if !b.writing {
+		if err := checkNaturalImplements(schema, typ, natural); err != nil {
+			return nil, err
+		}
+	}
+
+	codec, err := b.build(schema, natural, false)
+	if err != nil {
+		return nil, fmt.Errorf("building codec for %s: %w", natural, err)
+	}
+
+	return newInterfaceCodec(schema, typ, natural, codec), nil
+}
+
+// checkNaturalImplements checks we can decode schema into interface type typ:
+// the natural type for the schema must implement it.
+func checkNaturalImplements(schema Schema, typ, natural reflect.Type) error {
+	if !natural.Implements(typ) {
+		return fmt.Errorf("cannot decode %s into %s as %s does not implement it", schema.Type, typ, natural)
+	}
+	return nil
+}
+
+// newInterfaceCodec creates an interfaceCodec for interface type typ that uses
+// codec to read values of type natural
+func newInterfaceCodec(schema Schema, typ, natural reflect.Type, codec Codec) *interfaceCodec {
+	return &interfaceCodec{
+		schema:  schema,
+		rtype:   typ,
+		natural: natural,
+		codec:   codec,
+	}
+}
+
+func (c *interfaceCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	vp := r.Alloc(c.natural)
+	if err := c.codec.Read(r, vp); err != nil {
+		return err
+	}
+	reflect.NewAt(c.rtype, p).Elem().Set(reflect.NewAt(c.natural, vp).Elem())
+	return nil
+}
+
+func (c *interfaceCodec) Skip(r *ReadBuf) error {
+	return c.codec.Skip(r)
+}
+
+func (c *interfaceCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(c.rtype)
+}
+
+func (c *interfaceCodec) Omit(p unsafe.Pointer) bool {
+	return reflect.NewAt(c.rtype, p).Elem().IsNil()
+}
+
+func (c *interfaceCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	typ, vp := interfaceValue(c.rtype, p)
+	if typ == nil {
+		w.SetError(fmt.Errorf("cannot write nil %s as %s", c.rtype, c.schema.Type))
+		return
+	}
+	if typ == c.natural {
+		c.codec.Write(w, vp)
+		return
+	}
+
+	var iw *interfaceWriter
+	if v, ok := c.codecs.Load(typ); ok {
+		iw = v.(*interfaceWriter)
+	} else {
+		iw = &interfaceWriter{}
+		iw.codec, iw.err = buildWriteCodec(c.schema, typ)
+		c.codecs.Store(typ, iw)
+	}
+	if iw.err != nil {
+		w.SetError(fmt.Errorf("cannot write %s as %s: %w", typ, c.schema.Type, iw.err))
+		return
+	}
+	iw.codec.Write(w, vp)
+}
@@ -0,0 +1,211 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInterfaceCodec(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema Schema
+		data   []byte
+		exp    any
+	}{
+		{
+			name:   "boolean",
+			schema: Schema{Type: "boolean"},
+			data:   []byte{1},
+			exp:    true,
+		},
+		{
+			name:   "int",
+			schema: Schema{Type: "int"},
+			data:   []byte{6},
+			exp:    int32(3),
+		},
+		{
+			name:   "long",
+			schema: Schema{Type: "long"},
+			data:   []byte{6},
+			exp:    int64(3),
+		},
+		{
+			name:   "double",
+			schema: Schema{Type: "double"},
+			data:   []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f},
+			exp:    float64(1),
+		},
+		{
+			name:   "string",
+			schema: Schema{Type: "string"},
+			data:   []byte{6, 'f', 'o', 'o'},
+			exp:    "foo",
+		},
+		{
+			name:   "bytes",
+			schema: Schema{Type: "bytes"},
+			data:   []byte{4, 1, 2},
+			exp:    []byte{1, 2},
+		},
+		{
+			name:   "enum",
+			schema: colourSchema,
+			data:   []byte{2},
+			exp:    "GREEN",
+		},
+		{
+			name:   "fixed",
+			schema: Schema{Type: "fixed", Object: &SchemaObject{Name: "f", Size: 2}},
+			data:   []byte{1, 2},
+			exp:    [2]byte{1, 2},
+		},
+		{
+			name:   "array",
+			schema: Schema{Type: "array", Object: &SchemaObject{Items: Schema{Type: "int"}}},
+			data:   []byte{4, 2, 4, 0},
+			exp:    []any{int32(1), int32(2)},
+		},
+		{
+			name:   "map",
+			schema: Schema{Type: "map", Object: &SchemaObject{Values: Schema{Type: "string"}}},
+			data:   []byte{2, 2, 'a', 2, 'b', 0},
+			exp:    map[string]any{"a": "b"},
+		},
+		{
+			name: "record",
+			schema: Schema{
+				Type: "record",
+				Object: &SchemaObject{
+					Name: "r",
+					Fields: []SchemaRecordField{
+						{Name: "a", Type: Schema{Type: "long"}},
+						{Name: "b", Type: Schema{Type: "union", Union: []Schema{{Type: "null"}, {Type: "string"}}}},
+					},
+				},
+			},
+			data: []byte{2, 2, 2, 'x'},
+			exp:  map[string]any{"a": int64(1), "b": "x"},
+		},
+		{
+			name:   "union null",
+			schema: multiUnionSchema,
+			data:   []byte{0},
+			exp:    nil,
+		},
+		{
+			name:   "union long",
+			schema: multiUnionSchema,
+			data:   []byte{2, 6},
+			exp:    int64(3),
+		},
+		{
+			name:   "union string",
+			schema: multiUnionSchema,
+			data:   []byte{4, 6, 'f', 'o', 'o'},
+			exp:    "foo",
+		},
+		{
+			name:   "union record",
+			schema: multiUnionSchema,
+			data:   []byte{6, 2},
+			exp:    map[string]any{"a": int64(1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := buildCodec(test.schema, anyType, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewReadBuf(test.data)
+			var actual any
+			if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, actual); diff != "" {
+				t.Fatal(diff)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+
+			r = NewReadBuf(test.data)
+			if err := c.Skip(r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread after skip", r.Len())
+			}
+
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&actual))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.data, w.Bytes()); diff != "" {
+				t.Fatalf("written data differs: %s", diff)
+			}
+		})
+	}
+}
+
+func TestInterfaceCodecInRecord(t *testing.T) {
+	type record struct {
+		Name  string `json:"name"`
+		Value any    `json:"value"`
+	}
+
+	schema := Schema{
+		Type: "record",
+		Object: &SchemaObject{
+			Name: "record",
+			Fields: []SchemaRecordField{
+				{Name: "name", Type: Schema{Type: "string"}},
+				{Name: "value", Type: multiUnionSchema},
+			},
+		},
+	}
+
+	c, err := schema.Codec(record{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{6, 'j', 'i', 'm', 2, 84}
+	var actual record
+	if err := c.Read(NewReadBuf(data), unsafe.Pointer(&actual)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(record{Name: "jim", Value: int64(42)}, actual); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestInterfaceCodecNotImplemented(t *testing.T) {
+	if _, err := buildCodec(Schema{Type: "long"}, reflect.TypeFor[fmt.Stringer](), false); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// We can still write a value that implements the interface.
+	c, err := buildWriteCodec(Schema{Type: "long"}, reflect.TypeFor[fmt.Stringer]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v fmt.Stringer = time.Duration(1)
+	w := NewWriteBuf(nil)
+	c.Write(w, unsafe.Pointer(&v))
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{2}, w.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
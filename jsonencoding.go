@@ -0,0 +1,520 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+)
+
+// JSONEncoder writes values of type T using the AVRO JSON encoding. Each value
+// is written as a JSON value on its own line.
+//
+// In the JSON encoding records and maps are JSON objects, arrays are JSON
+// arrays, and bytes and fixed are strings where each code point from 0 to 255
+// is one byte. A non-null union value is an object with a single key giving
+// the name of the branch, e.g. {"string": "hat"}. The NaN and infinite floating
+// point values are written as the strings "NaN", "Infinity" and "-Infinity".
+//
+// Go values are mapped onto the schema in the same way as for the binary
+// encoding. JSONEncoder is not safe for concurrent use.
+type JSONEncoder[T any] struct {
+	schema Schema
+	codec  Codec
+	enc    *jsontext.Encoder
+	wb     WriteBuf
+}
+
+// NewJSONEncoder returns a JSONEncoder that writes values of type T to w using
+// the given schema. Use SchemaForType to find a schema for T.
+func NewJSONEncoder[T any](w io.Writer, schema Schema) (*JSONEncoder[T], error) {
+	schema, err := resolveNames(schema)
+	if err != nil {
+		return nil, fmt.Errorf("resolving schema names: %w", err)
+	}
+	c, err := buildWriteCodec(schema, reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+	return &JSONEncoder[T]{
+		schema: schema,
+		codec:  c,
+		enc:    jsontext.NewEncoder(w),
+	}, nil
+}
+
+// Encode writes v to the underlying writer.
+func (e *JSONEncoder[T]) Encode(v *T) error {
+	e.wb.Reset()
+	e.codec.Write(&e.wb, unsafe.Pointer(v))
+	if err := e.wb.Err(); err != nil {
+		return fmt.Errorf("encoding value: %w", err)
+	}
+	// We write the binary encoding first, then convert it to JSON using the
+	// schema. This means all the Go type handling is shared with the binary
+	// encoding.
+	r := ReadBuf{buf: e.wb.Bytes()}
+	if err := binaryToJSON(e.enc, e.schema, &r); err != nil {
+		return fmt.Errorf("writing JSON: %w", err)
+	}
+	return nil
+}
+
+// JSONDecoder reads values of type T in the AVRO JSON encoding (see
+// JSONEncoder). It is not safe for concurrent use.
+type JSONDecoder[T any] struct {
+	schema Schema
+	codec  Codec
+	dec    *jsontext.Decoder
+	wb     WriteBuf
+}
+
+// NewJSONDecoder returns a JSONDecoder that reads values of type T from r. The
+// data in r should be a sequence of JSON values matching the given schema.
+func NewJSONDecoder[T any](r io.Reader, schema Schema) (*JSONDecoder[T], error) {
+	schema, err := resolveNames(schema)
+	if err != nil {
+		return nil, fmt.Errorf("resolving schema names: %w", err)
+	}
+	c, err := buildCodec(schema, reflect.TypeFor[T](), false)
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+	return &JSONDecoder[T]{
+		schema: schema,
+		codec:  c,
+		dec:    jsontext.NewDecoder(r),
+	}, nil
+}
+
+// Decode reads the next value from the underlying reader into v. It returns
+// io.EOF when there are no more values.
+func (d *JSONDecoder[T]) Decode(v *T) error {
+	data, err := d.dec.ReadValue()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return fmt.Errorf("reading JSON: %w", err)
+	}
+
+	// We convert the JSON to the binary encoding, then decode that.
+	d.wb.Reset()
+	if err := jsonToBinary(&d.wb, d.schema, data); err != nil {
+		return err
+	}
+
+	var zero T
+	*v = zero
+	r := NewReadBuf(d.wb.Bytes())
+	if err := d.codec.Read(r, unsafe.Pointer(v)); err != nil {
+		return fmt.Errorf("decoding value: %w", err)
+	}
+	return nil
+}
+
+// binaryToJSON reads a value with the given schema in the AVRO binary encoding
+// from r, and writes it to enc in the AVRO JSON encoding. The schema's names
+// must be resolved.
+func binaryToJSON(enc *jsontext.Encoder, schema Schema, r *ReadBuf) error {
+	switch schema.Type {
+	case "null":
+		return enc.WriteToken(jsontext.Null)
+
+	case "boolean":
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("reading boolean: %w", err)
+		}
+		return enc.WriteToken(jsontext.Bool(b != 0))
+
+	case "int", "long":
+		v, err := r.Varint()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", schema.Type, err)
+		}
+		return enc.WriteToken(jsontext.Int(v))
+
+	case "float":
+		data, err := r.Next(4)
+		if err != nil {
+			return fmt.Errorf("reading float: %w", err)
+		}
+		return writeJSONFloat(enc, float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), 32)
+
+	case "double":
+		data, err := r.Next(8)
+		if err != nil {
+			return fmt.Errorf("reading double: %w", err)
+		}
+		return writeJSONFloat(enc, math.Float64frombits(binary.LittleEndian.Uint64(data)), 64)
+
+	case "bytes", "string":
+		l, err := r.Varint()
+		if err != nil {
+			return fmt.Errorf("reading %s length: %w", schema.Type, err)
+		}
+		if l < 0 {
+			return fmt.Errorf("negative %s length %d", schema.Type, l)
+		}
+		data, err := r.Next(int(l))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", schema.Type, err)
+		}
+		if schema.Type == "bytes" {
+			return enc.WriteToken(jsontext.String(bytesToJSONString(data)))
+		}
+		return enc.WriteToken(jsontext.String(string(data)))
+
+	case "fixed":
+		data, err := r.Next(schema.Object.Size)
+		if err != nil {
+			return fmt.Errorf("reading fixed: %w", err)
+		}
+		return enc.WriteToken(jsontext.String(bytesToJSONString(data)))
+
+	case "enum":
+		index, err := r.Varint()
+		if err != nil {
+			return fmt.Errorf("reading enum: %w", err)
+		}
+		if index < 0 || index >= int64(len(schema.Object.Symbols)) {
+			return fmt.Errorf("enum index %d out of range for enum %s", index, schema.Object.Name)
+		}
+		return enc.WriteToken(jsontext.String(schema.Object.Symbols[index]))
+
+	case "array", "map":
+		begin, end := jsontext.BeginArray, jsontext.EndArray
+		if schema.Type == "map" {
+			begin, end = jsontext.BeginObject, jsontext.EndObject
+		}
+		if err := enc.WriteToken(begin); err != nil {
+			return err
+		}
+		for {
+			count, err := r.Varint()
+			if err != nil {
+				return fmt.Errorf("reading %s block count: %w", schema.Type, err)
+			}
+			if count == 0 {
+				break
+			}
+			if count < 0 {
+				count = -count
+				if _, err := r.Varint(); err != nil {
+					return fmt.Errorf("reading %s block size: %w", schema.Type, err)
+				}
+			}
+			for range count {
+				if schema.Type == "array" {
+					if err := binaryToJSON(enc, schema.Object.Items, r); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := binaryToJSON(enc, Schema{Type: "string"}, r); err != nil {
+					return fmt.Errorf("map key: %w", err)
+				}
+				if err := binaryToJSON(enc, schema.Object.Values, r); err != nil {
+					return err
+				}
+			}
+		}
+		return enc.WriteToken(end)
+
+	case "union":
+		index, err := r.Varint()
+		if err != nil {
+			return fmt.Errorf("reading union branch: %w", err)
+		}
+		if index < 0 || index >= int64(len(schema.Union)) {
+			return fmt.Errorf("union branch %d out of range", index)
+		}
+		branch := schema.Union[index]
+		if branch.Type == "null" {
+			return enc.WriteToken(jsontext.Null)
+		}
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return err
+		}
+		if err := enc.WriteToken(jsontext.String(jsonBranchName(branch))); err != nil {
+			return err
+		}
+		if err := binaryToJSON(enc, branch, r); err != nil {
+			return err
+		}
+		return enc.WriteToken(jsontext.EndObject)
+
+	case "record":
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return err
+		}
+		for _, f := range schema.Object.Fields {
+			if err := enc.WriteToken(jsontext.String(f.Name)); err != nil {
+				return err
+			}
+			if err := binaryToJSON(enc, f.Type, r); err != nil {
+				return fmt.Errorf("field %q: %w", f.Name, err)
+			}
+		}
+		return enc.WriteToken(jsontext.EndObject)
+	}
+
+	return fmt.Errorf("unexpected schema type %q", schema.Type)
+}
+
+func writeJSONFloat(enc *jsontext.Encoder, f float64, bitSize int) error {
+	switch {
+	case math.IsNaN(f):
+		return enc.WriteToken(jsontext.String("NaN"))
+	case math.IsInf(f, 1):
+		return enc.WriteToken(jsontext.String("Infinity"))
+	case math.IsInf(f, -1):
+		return enc.WriteToken(jsontext.String("-Infinity"))
+	}
+	// Format with the precision of the original type, so a float32 0.1 is
+	// written as 0.1 rather than 0.10000000149011612
+	return enc.WriteValue(strconv.AppendFloat(nil, f, 'g', -1, bitSize))
+}
+
+// bytesToJSONString converts bytes to a string with a code point for each byte,
+// as used for bytes and fixed in the JSON encoding.
+func bytesToJSONString(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// jsonBranchName is the name used for a union branch in the JSON encoding. It's
+// the full name for named types and the type otherwise.
+func jsonBranchName(s Schema) string {
+	switch s.Type {
+	case "record", "enum", "fixed":
+		return s.Object.FullName()
+	}
+	return s.Type
+}
+
+// jsonToBinary converts data, a value with the given schema in the AVRO JSON
+// encoding, to the AVRO binary encoding and writes it to w. The schema's names
+// must be resolved.
+func jsonToBinary(w *WriteBuf, schema Schema, data jsontext.Value) error {
+	switch schema.Type {
+	case "null":
+		if data.Kind() != 'n' {
+			return fmt.Errorf("expected null, got %s", data)
+		}
+		return nil
+
+	case "boolean":
+		var v bool
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid boolean %s: %w", data, err)
+		}
+		if v {
+			w.Byte(1)
+		} else {
+			w.Byte(0)
+		}
+		return nil
+
+	case "int":
+		var v int32
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid int %s: %w", data, err)
+		}
+		w.Varint(int64(v))
+		return nil
+
+	case "long":
+		var v int64
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid long %s: %w", data, err)
+		}
+		w.Varint(v)
+		return nil
+
+	case "float":
+		v, err := jsonFloat(data)
+		if err != nil {
+			return err
+		}
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(float32(v)))
+		return nil
+
+	case "double":
+		v, err := jsonFloat(data)
+		if err != nil {
+			return err
+		}
+		w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+		return nil
+
+	case "string":
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid string %s: %w", data, err)
+		}
+		w.Varint(int64(len(v)))
+		w.Write([]byte(v))
+		return nil
+
+	case "bytes":
+		v, err := bytesDefault(data)
+		if err != nil {
+			return err
+		}
+		w.Varint(int64(len(v)))
+		w.Write(v)
+		return nil
+
+	case "fixed":
+		v, err := bytesDefault(data)
+		if err != nil {
+			return err
+		}
+		if len(v) != schema.Object.Size {
+			return fmt.Errorf("value for fixed of size %d has %d bytes", schema.Object.Size, len(v))
+		}
+		w.Write(v)
+		return nil
+
+	case "enum":
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid enum %s: %w", data, err)
+		}
+		for i, sym := range schema.Object.Symbols {
+			if sym == v {
+				w.Varint(int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a symbol of enum %s", v, schema.Object.Name)
+
+	case "array":
+		var items []jsontext.Value
+		if err := json.Unmarshal(data, &items); err != nil {
+			return fmt.Errorf("invalid array %s: %w", data, err)
+		}
+		if len(items) > 0 {
+			w.Varint(int64(len(items)))
+			for i, item := range items {
+				if err := jsonToBinary(w, schema.Object.Items, item); err != nil {
+					return fmt.Errorf("array item %d: %w", i, err)
+				}
+			}
+		}
+		w.Varint(0)
+		return nil
+
+	case "map":
+		var entries map[string]jsontext.Value
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("invalid map %s: %w", data, err)
+		}
+		if len(entries) > 0 {
+			w.Varint(int64(len(entries)))
+			for key, entry := range entries {
+				w.Varint(int64(len(key)))
+				w.Write([]byte(key))
+				if err := jsonToBinary(w, schema.Object.Values, entry); err != nil {
+					return fmt.Errorf("map entry %q: %w", key, err)
+				}
+			}
+		}
+		w.Varint(0)
+		return nil
+
+	case "union":
+		if data.Kind() == 'n' {
+			for i, branch := range schema.Union {
+				if branch.Type == "null" {
+					w.Varint(int64(i))
+					return nil
+				}
+			}
+			return fmt.Errorf("union has no null branch")
+		}
+		var wrapped map[string]jsontext.Value
+		if err := json.Unmarshal(data, &wrapped); err != nil || len(wrapped) != 1 {
+			return fmt.Errorf("union value %s should be null or an object with a single key naming the branch", data)
+		}
+		var name string
+		var value jsontext.Value
+		for name, value = range wrapped {
+		}
+		for i, branch := range schema.Union {
+			if branchName := jsonBranchName(branch); branchName == name || unqualifiedName(branchName) == name {
+				w.Varint(int64(i))
+				if err := jsonToBinary(w, branch, value); err != nil {
+					return fmt.Errorf("union branch %s: %w", name, err)
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("union has no branch %q", name)
+
+	case "record":
+		var fields map[string]jsontext.Value
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("invalid record %s: %w", data, err)
+		}
+		for _, f := range schema.Object.Fields {
+			value, ok := fields[f.Name]
+			if !ok {
+				// Missing fields take their default. Note defaults are not
+				// in the JSON encoding: union defaults aren't wrapped.
+				if len(f.Default) == 0 {
+					return fmt.Errorf("record %s has no value for field %q, and the field has no default", schema.Object.Name, f.Name)
+				}
+				encoded, err := encodeDefault(f.Type, f.Default)
+				if err != nil {
+					return fmt.Errorf("field %q: %w", f.Name, err)
+				}
+				w.Write(encoded)
+				continue
+			}
+			if err := jsonToBinary(w, f.Type, value); err != nil {
+				return fmt.Errorf("field %q: %w", f.Name, err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unexpected schema type %q", schema.Type)
+}
+
+// jsonFloat decodes a float from the JSON encoding, accepting the strings
+// "NaN", "Infinity" and "-Infinity".
+func jsonFloat(data jsontext.Value) (float64, error) {
+	if data.Kind() == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return 0, err
+		}
+		switch s {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return 0, fmt.Errorf("invalid floating point value %s", data)
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, fmt.Errorf("invalid floating point value %s: %w", data, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,191 @@
+package avro_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/philpearl/avro"
+)
+
+type jsonInner struct {
+	Score float64 `json:"score"`
+}
+
+type jsonRecord struct {
+	Name   string           `json:"name"`
+	Count  int32            `json:"count"`
+	Data   []byte           `json:"data"`
+	Colour string           `json:"colour" avro:"enum=RED|GREEN"`
+	Nick   *string          `json:"nick"`
+	Tags   []string         `json:"tags"`
+	Attrs  map[string]int64 `json:"attrs"`
+	Inner  jsonInner        `json:"inner"`
+	Ratio  float64          `json:"ratio"`
+	Maybe  *jsonInner       `json:"maybe"`
+}
+
+func TestJSONEncoding(t *testing.T) {
+	schema, err := avro.SchemaForType(jsonRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nick := "bob"
+	in := []jsonRecord{
+		{
+			Name:   "robert",
+			Count:  3,
+			Data:   []byte{0, 0x7F, 0xFF},
+			Colour: "GREEN",
+			Nick:   &nick,
+			Tags:   []string{"a", "b"},
+			Attrs:  map[string]int64{"x": 1},
+			Inner:  jsonInner{Score: 0.1},
+			Ratio:  math.Inf(-1),
+			Maybe:  &jsonInner{Score: 2},
+		},
+		{
+			Name:   "empty",
+			Colour: "RED",
+		},
+	}
+
+	var buf bytes.Buffer
+	enc, err := avro.NewJSONEncoder[jsonRecord](&buf, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range in {
+		if err := enc.Encode(&in[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := `{"name":"robert","count":3,"data":"\u0000` + "\x7f" + `ÿ","colour":"GREEN","nick":{"string":"bob"},"tags":["a","b"],"attrs":{"x":1},"inner":{"score":0.1},"ratio":"-Infinity","maybe":{"github.com.philpearl.avro_test.jsonInner":{"score":2}}}
+{"name":"empty","count":0,"data":"","colour":"RED","nick":null,"tags":[],"attrs":{},"inner":{"score":0},"ratio":0,"maybe":null}
+`
+	if diff := cmp.Diff(exp, buf.String()); diff != "" {
+		t.Fatalf("JSON not as expected (-want +got):\n%s", diff)
+	}
+
+	dec, err := avro.NewJSONDecoder[jsonRecord](&buf, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []jsonRecord
+	for {
+		var v jsonRecord
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatal(err)
+		}
+		out = append(out, v)
+	}
+	if diff := cmp.Diff(in, out, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("decoded values not as expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONDecoding(t *testing.T) {
+	schema, err := avro.SchemaFromString(`{
+		"type": "record",
+		"name": "r",
+		"namespace": "ns",
+		"fields": [
+			{"name": "a", "type": ["null", "long", {"type": "enum", "name": "E", "symbols": ["X", "Y"]}]},
+			{"name": "b", "type": "double", "default": 1.5},
+			{"name": "c", "type": {"type": "fixed", "name": "F", "size": 2}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type record struct {
+		A any     `json:"a"`
+		B float64 `json:"b"`
+		C [2]byte `json:"c"`
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		exp  record
+	}{
+		{name: "full name", in: `{"a": {"ns.E": "Y"}, "b": 2, "c": "ab"}`, exp: record{A: "Y", B: 2, C: [2]byte{'a', 'b'}}},
+		{name: "short name", in: `{"a": {"E": "X"}, "c": "ab"}`, exp: record{A: "X", B: 1.5, C: [2]byte{'a', 'b'}}},
+		{name: "primitive branch", in: `{"a": {"long": 12}, "b": "NaN", "c": "ÿ\u0000", "extra": true}`, exp: record{A: int64(12), B: math.NaN(), C: [2]byte{0xFF, 0}}},
+		{name: "null branch", in: `{"a": null, "b": 0, "c": "zz"}`, exp: record{B: 0, C: [2]byte{'z', 'z'}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dec, err := avro.NewJSONDecoder[record](strings.NewReader(test.in), schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out record
+			if err := dec.Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, out, cmpopts.EquateNaNs()); diff != "" {
+				t.Fatalf("decoded value not as expected (-want +got):\n%s", diff)
+			}
+			if err := dec.Decode(&out); !errors.Is(err, io.EOF) {
+				t.Fatalf("expected EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONDecodingErrors(t *testing.T) {
+	schema, err := avro.SchemaFromString(`{
+		"type": "record",
+		"name": "r",
+		"fields": [
+			{"name": "a", "type": ["null", "int"]},
+			{"name": "b", "type": {"type": "enum", "name": "E", "symbols": ["X"]}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type record struct {
+		A *int32 `json:"a"`
+		B string `json:"b"`
+	}
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "unwrapped union", in: `{"a": 1, "b": "X"}`},
+		{name: "unknown branch", in: `{"a": {"long": 1}, "b": "X"}`},
+		{name: "int out of range", in: `{"a": {"int": 3000000000}, "b": "X"}`},
+		{name: "bad symbol", in: `{"a": null, "b": "Z"}`},
+		{name: "missing field", in: `{"a": null}`},
+		{name: "not an object", in: `[]`},
+		{name: "bad JSON", in: `{"a": `},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dec, err := avro.NewJSONDecoder[record](strings.NewReader(test.in), schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out record
+			if err := dec.Decode(&out); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package avro
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"unsafe"
 )
 
@@ -104,6 +105,12 @@ func (m *MapCodec) Omit(p unsafe.Pointer) bool {
 }
 
 func (m *MapCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	if m.rtype == nil {
+		// The codec was built without a Go type, so it can only read
+		w.SetError(fmt.Errorf("map codec has no Go type to write"))
+		return
+	}
+
 	// p is a pointer to a map pointer, but maps are already pointery
 	p = *(*unsafe.Pointer)(p)
 
@@ -130,6 +137,10 @@ func (m *MapCodec) Write(w *WriteBuf, p unsafe.Pointer) {
 
 		sc.Write(w, k)
 		m.valueCodec.Write(w, v)
+		if w.err != nil {
+			w.addErrorPath("[" + strconv.Quote(*(*string)(k)) + "]")
+			return
+		}
 
 		mapiternext(iter)
 	}
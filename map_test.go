@@ -1,6 +1,8 @@
 package avro
 
 import (
+	"errors"
+	"math"
 	"reflect"
 	"testing"
 	"unsafe"
@@ -147,3 +149,29 @@ func TestMapCodec(t *testing.T) {
 		})
 	}
 }
+
+func TestMapCodecWriteErrors(t *testing.T) {
+	t.Run("no type", func(t *testing.T) {
+		m := map[string][]byte{"a": {1}}
+		c := MapCodec{valueCodec: BytesCodec{}}
+		w := NewWriteBuf(nil)
+		c.Write(w, unsafe.Pointer(&m))
+		if w.Err() == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("bad value", func(t *testing.T) {
+		m := map[string]int64{"a": math.MaxInt64}
+		c := MapCodec{rtype: reflect.TypeOf(m), valueCodec: Int64Codec{is32: true}}
+		w := NewWriteBuf(nil)
+		c.Write(w, unsafe.Pointer(&m))
+		var ee *EncodeError
+		if !errors.As(w.Err(), &ee) {
+			t.Fatalf("expected an EncodeError, got %v", w.Err())
+		}
+		if path := ee.Path(); path != `["a"]` {
+			t.Fatalf("path is %s", path)
+		}
+	})
+}
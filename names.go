@@ -0,0 +1,149 @@
+package avro
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// resolveNames returns a copy of schema in which references to named types
+// (records, enums and fixed) are replaced by the types they refer to. A
+// reference is a schema whose type is the name of a type defined earlier in
+// the schema. Names are qualified by namespaces following the rules in the
+// AVRO specification.
+//
+// Recursive types refer to an enclosing type, so the copy may contain cycles:
+// a record may be reachable from its own fields. The original schema is not
+// modified, and schemas that have already been resolved can be resolved
+// again.
+func resolveNames(schema Schema) (Schema, error) {
+	r := nameResolver{
+		names:  make(map[string]Schema),
+		copies: make(map[*SchemaObject]*SchemaObject),
+	}
+	return r.resolve(schema, "")
+}
+
+type nameResolver struct {
+	// names maps the full names of named types to their definitions
+	names map[string]Schema
+	// copies maps the objects of named types in the original schema to their
+	// copies.
+	copies map[*SchemaObject]*SchemaObject
+}
+
+func (r *nameResolver) resolve(s Schema, namespace string) (Schema, error) {
+	switch s.Type {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return s, nil
+
+	case "union":
+		union := make([]Schema, len(s.Union))
+		for i, u := range s.Union {
+			var err error
+			if union[i], err = r.resolve(u, namespace); err != nil {
+				return Schema{}, err
+			}
+		}
+		return Schema{Type: s.Type, Union: union}, nil
+
+	case "array", "map":
+		if s.Object == nil {
+			return Schema{}, fmt.Errorf("%s schema does not have object", s.Type)
+		}
+		o := *s.Object
+		var err error
+		if o.Items.Type != "" {
+			if o.Items, err = r.resolve(o.Items, namespace); err != nil {
+				return Schema{}, err
+			}
+		}
+		if o.Values.Type != "" {
+			if o.Values, err = r.resolve(o.Values, namespace); err != nil {
+				return Schema{}, err
+			}
+		}
+		return Schema{Type: s.Type, Object: &o}, nil
+
+	case "record", "enum", "fixed":
+		if s.Object == nil {
+			return Schema{}, fmt.Errorf("%s schema does not have object", s.Type)
+		}
+		if o, ok := r.copies[s.Object]; ok {
+			return Schema{Type: s.Type, Object: o}, nil
+		}
+
+		o := *s.Object
+		// Names without a namespace of their own take the namespace of the
+		// enclosing named type.
+		if o.Namespace == "" && !strings.ContainsRune(o.Name, '.') {
+			o.Namespace = namespace
+		}
+		r.copies[s.Object] = &o
+		def := Schema{Type: s.Type, Object: &o}
+
+		fullName := o.FullName()
+		var prev Schema
+		var redefined bool
+		if fullName != "" {
+			if prev, redefined = r.names[fullName]; !redefined {
+				r.names[fullName] = def
+			}
+		}
+
+		if s.Type == "record" {
+			// Fields are in the namespace of the record
+			ns := namespace
+			if i := strings.LastIndexByte(fullName, '.'); i >= 0 {
+				ns = fullName[:i]
+			} else if fullName != "" {
+				ns = ""
+			}
+			o.Fields = make([]SchemaRecordField, len(s.Object.Fields))
+			for i, f := range s.Object.Fields {
+				var err error
+				if f.Type, err = r.resolve(f.Type, ns); err != nil {
+					return Schema{}, fmt.Errorf("field %q: %w", f.Name, err)
+				}
+				o.Fields[i] = f
+			}
+		}
+
+		if redefined {
+			// AVRO doesn't allow a name to be defined twice. We accept a second
+			// definition that's the same as the first and use the first in its
+			// place, but a different definition is an error.
+			if !sameDefinition(prev, def) {
+				return Schema{}, fmt.Errorf("%s is defined twice with different definitions", fullName)
+			}
+			r.copies[s.Object] = prev.Object
+			return prev, nil
+		}
+		return def, nil
+	}
+
+	// Anything else should be a reference to a named type.
+	if !strings.ContainsRune(s.Type, '.') && namespace != "" {
+		if def, ok := r.names[namespace+"."+s.Type]; ok {
+			return def, nil
+		}
+	}
+	if def, ok := r.names[s.Type]; ok {
+		return def, nil
+	}
+	return Schema{}, fmt.Errorf("type %q is not a primitive type or a named type defined earlier in the schema", s.Type)
+}
+
+// sameDefinition reports whether two resolved definitions of a named type have
+// the same Parsing Canonical Form.
+func sameDefinition(a, b Schema) bool {
+	ca := canonicalWriter{defined: make(map[string]bool)}
+	cb := canonicalWriter{defined: make(map[string]bool)}
+	if err := ca.write(a); err != nil {
+		return false
+	}
+	if err := cb.write(b); err != nil {
+		return false
+	}
+	return bytes.Equal(ca.buf, cb.buf)
+}
@@ -0,0 +1,146 @@
+package avro
+
+import (
+	"testing"
+)
+
+func TestResolveNames(t *testing.T) {
+	schema, err := SchemaFromString(`{
+		"type": "record",
+		"name": "Outer",
+		"namespace": "com.acme",
+		"fields": [
+			{"name": "a", "type": {"type": "fixed", "name": "Hash", "size": 4}},
+			{"name": "b", "type": "Hash"},
+			{"name": "c", "type": "com.acme.Hash"},
+			{"name": "d", "type": {"type": "enum", "name": "other.Colour", "symbols": ["RED"]}},
+			{"name": "e", "type": {"type": "array", "items": "other.Colour"}},
+			{"name": "f", "type": ["null", "Outer"]},
+			{"name": "g", "type": {
+				"type": "record",
+				"name": "Inner",
+				"namespace": "com.other",
+				"fields": [
+					{"name": "h", "type": "com.acme.Hash"}
+				]
+			}},
+			{"name": "i", "type": {"type": "map", "values": "com.other.Inner"}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := resolveNames(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := resolved.Object.Fields
+	hash := fields[0].Type.Object
+	if hash.FullName() != "com.acme.Hash" {
+		t.Errorf("fixed has full name %q", hash.FullName())
+	}
+	if fields[1].Type.Object != hash || fields[1].Type.Type != "fixed" {
+		t.Errorf("unqualified reference not resolved: %#v", fields[1].Type)
+	}
+	if fields[2].Type.Object != hash {
+		t.Errorf("qualified reference not resolved: %#v", fields[2].Type)
+	}
+	colour := fields[3].Type.Object
+	if colour.FullName() != "other.Colour" {
+		t.Errorf("enum has full name %q", colour.FullName())
+	}
+	if fields[4].Type.Object.Items.Object != colour {
+		t.Errorf("array items not resolved: %#v", fields[4].Type.Object.Items)
+	}
+	if fields[5].Type.Union[1].Object != resolved.Object {
+		t.Errorf("recursive reference not resolved: %#v", fields[5].Type.Union[1])
+	}
+	inner := fields[6].Type.Object
+	if inner.Fields[0].Type.Object != hash {
+		t.Errorf("reference in other namespace not resolved: %#v", inner.Fields[0].Type)
+	}
+	if fields[7].Type.Object.Values.Object != inner {
+		t.Errorf("map values not resolved: %#v", fields[7].Type.Object.Values)
+	}
+
+	// The original schema is unchanged
+	if schema.Object.Fields[1].Type.Type != "Hash" {
+		t.Errorf("original schema modified: %#v", schema.Object.Fields[1].Type)
+	}
+
+	// Resolving again copes with the cycles
+	again, err := resolveNames(resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Object.Fields[5].Type.Union[1].Object != again.Object {
+		t.Errorf("recursive reference not preserved")
+	}
+}
+
+func TestResolveNamesRedefined(t *testing.T) {
+	// AVRO doesn't allow names to be redefined, but we accept a second
+	// definition that's the same as the first.
+	schema, err := SchemaFromString(`{
+		"type": "record",
+		"name": "a",
+		"fields": [
+			{"name": "a", "type": {"type": "fixed", "name": "f", "size": 4}},
+			{"name": "b", "type": {"type": "fixed", "name": "f", "size": 4, "doc": "same again"}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := resolveNames(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := resolved.Object.Fields
+	if fields[0].Type.Object != fields[1].Type.Object {
+		t.Errorf("second definition not replaced by the first: %#v", fields[1].Type)
+	}
+}
+
+func TestResolveNamesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name:   "unknown",
+			schema: `{"type": "record", "name": "a", "fields": [{"name": "a", "type": "b"}]}`,
+		},
+		{
+			name:   "wrong namespace",
+			schema: `{"type": "record", "name": "a", "namespace": "x", "fields": [{"name": "a", "type": {"type": "fixed", "name": "y.f", "size": 1}}, {"name": "b", "type": "f"}]}`,
+		},
+		{
+			name:   "redefined differently",
+			schema: `{"type": "record", "name": "a", "fields": [{"name": "a", "type": {"type": "enum", "name": "e", "symbols": ["X"]}}, {"name": "b", "type": {"type": "enum", "name": "e", "symbols": ["Y"]}}]}`,
+		},
+		{
+			name:   "record redefines itself",
+			schema: `{"type": "record", "name": "a", "fields": [{"name": "a", "type": {"type": "record", "name": "a", "fields": [{"name": "b", "type": "long"}]}}]}`,
+		},
+		{
+			name:   "used before defined",
+			schema: `{"type": "record", "name": "a", "fields": [{"name": "a", "type": "f"}, {"name": "b", "type": {"type": "fixed", "name": "f", "size": 1}}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := SchemaFromString(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := resolveNames(schema); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// WithParallelism causes ReadFile to decompress and decode blocks of the file
+// on the given number of worker goroutines. This can make reading large files
+// much faster on machines with many cores. The callback is still only called
+// from one goroutine at a time, and by default records are passed to it in the
+// order they appear in the file.
+//
+// Each record passed to the callback has its own ResourceBank, as when reading
+// sequentially. The record itself is allocated in that ResourceBank, so if out
+// is a pointer it is not used to hold the records.
+func WithParallelism(workers int) ReadOption {
+	return func(rc *readConfig) {
+		rc.workers = workers
+	}
+}
+
+// WithUnorderedDelivery is used with WithParallelism. It allows ReadFile to
+// pass records to the callback as soon as the block they're in has been
+// decoded, rather than in the order they appear in the file. Records from the
+// same block are still passed in order.
+func WithUnorderedDelivery() ReadOption {
+	return func(rc *readConfig) {
+		rc.unordered = true
+	}
+}
+
+// decodedRecord is a record decoded by a worker, waiting to be passed to the
+// callback.
+type decodedRecord struct {
+	p  unsafe.Pointer
+	rb *ResourceBank
+}
+
+// decodedBlock holds the records decoded from a block. If there's an error it
+// follows the records that were decoded successfully.
+type decodedBlock struct {
+	records []decodedRecord
+	err     error
+}
+
+// blockJob is a block for a worker to decompress and decode. The result is
+// sent to out.
+type blockJob struct {
+	block block
+	out   chan<- decodedBlock
+}
+
+// readFileParallel reads the blocks of an AVRO file after the header, and
+// decompresses and decodes them on rc.workers goroutines.
+func readFileParallel(r Reader, fh FileHeader, codec Codec, typ reflect.Type, cb func(val unsafe.Pointer, rb *ResourceBank) error, rc *readConfig) error {
+	done := make(chan struct{})
+	jobs := make(chan blockJob, rc.workers)
+	// With ordered delivery, order receives a channel for each block's result
+	// in the order the blocks are in the file. Otherwise all results go to the
+	// results channel.
+	order := make(chan chan decodedBlock, 2*rc.workers)
+	results := make(chan decodedBlock, rc.workers)
+
+	var wg sync.WaitGroup
+	// Make sure everything has stopped, and in particular that we've stopped
+	// reading from r, before returning.
+	defer wg.Wait()
+	defer close(done)
+
+	decoders := make([]CompressionCodec, rc.workers)
+	for i := range decoders {
+		var err error
+		if decoders[i], err = fh.decoder(); err != nil {
+			return err
+		}
+	}
+
+	// resultChan returns the channel the result for the next block should be
+	// sent to.
+	resultChan := func() (chan<- decodedBlock, bool) {
+		if rc.unordered {
+			return results, true
+		}
+		ch := make(chan decodedBlock, 1)
+		select {
+		case order <- ch:
+			return ch, true
+		case <-done:
+			return nil, false
+		}
+	}
+
+	var readerWG sync.WaitGroup
+	readerWG.Add(1)
+	wg.Go(func() {
+		defer readerWG.Done()
+		defer close(jobs)
+		defer close(order)
+		for b, err := range readRawBlocks(r, fh.Sync, false) {
+			out, ok := resultChan()
+			if !ok {
+				return
+			}
+			if err != nil {
+				select {
+				case out <- decodedBlock{err: err}:
+				case <-done:
+				}
+				return
+			}
+			select {
+			case jobs <- blockJob{block: b, out: out}:
+			case <-done:
+				return
+			}
+		}
+	})
+
+	var workerWG sync.WaitGroup
+	for _, decoder := range decoders {
+		workerWG.Add(1)
+		wg.Go(func() {
+			defer workerWG.Done()
+			br := &ReadBuf{}
+			for job := range jobs {
+				res := decodeBlock(br, decoder, codec, typ, job.block)
+				select {
+				case job.out <- res:
+				case <-done:
+					return
+				}
+			}
+		})
+	}
+
+	// Once the reader and workers have finished there are no more results.
+	wg.Go(func() {
+		readerWG.Wait()
+		workerWG.Wait()
+		close(results)
+	})
+
+	deliver := func(res decodedBlock) error {
+		for i, rec := range res.records {
+			if err := cb(rec.p, rec.rb); err != nil {
+				// Nobody will see the remaining records
+				for _, rec := range res.records[i+1:] {
+					rec.rb.Close()
+				}
+				return err
+			}
+		}
+		return res.err
+	}
+
+	if rc.unordered {
+		for res := range results {
+			if err := deliver(res); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for ch := range order {
+		if err := deliver(<-ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBlock decompresses and decodes the records in a block. Each record is
+// allocated individually, and the data it refers to is allocated in its own
+// ResourceBank.
+func decodeBlock(br *ReadBuf, decoder CompressionCodec, codec Codec, typ reflect.Type, b block) decodedBlock {
+	data, err := decoder.Decompress(b.data)
+	if err != nil {
+		return decodedBlock{err: fmt.Errorf("decompress failed: %w", err)}
+	}
+	br.Reset(data)
+
+	// We don't trust the count in the file enough to use it to size the
+	// records slice.
+	var res decodedBlock
+	for i := range b.count {
+		// Allocating the record in the bank would cost a whole array of
+		// records, as each record has its own bank.
+		p := reflect.New(typ).UnsafePointer()
+		if err := codec.Read(br, p); err != nil {
+			res.err = fmt.Errorf("failed to read item %d in file. %w", i, err)
+			br.ExtractResourceBank().Close()
+			return res
+		}
+		res.records = append(res.records, decodedRecord{p: p, rb: br.ExtractResourceBank()})
+	}
+	return res
+}
@@ -0,0 +1,227 @@
+package avro_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+type parallelRecord struct {
+	ID   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func writeParallelFile(t *testing.T, count int) ([]byte, []parallelRecord) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[parallelRecord](&buf, avro.CompressionSnappy, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := make([]parallelRecord, count)
+	for i := range records {
+		records[i] = parallelRecord{
+			ID:   int64(i),
+			Name: "record " + strconv.Itoa(i),
+			Tags: []string{strconv.Itoa(i % 7)},
+		}
+		if err := enc.Encode(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), records
+}
+
+func TestReadFileParallel(t *testing.T) {
+	data, exp := writeParallelFile(t, 1000)
+
+	tests := []struct {
+		name string
+		out  any
+		opts []avro.ReadOption
+	}{
+		{name: "ordered", out: parallelRecord{}, opts: []avro.ReadOption{avro.WithParallelism(4)}},
+		{name: "ordered pointer", out: &parallelRecord{}, opts: []avro.ReadOption{avro.WithParallelism(3)}},
+		{name: "unordered", out: parallelRecord{}, opts: []avro.ReadOption{avro.WithParallelism(4), avro.WithUnorderedDelivery()}},
+		{name: "resolved", out: parallelRecord{}, opts: []avro.ReadOption{avro.WithParallelism(2), avro.WithSchemaResolution()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []parallelRecord
+			if err := avro.ReadFile(bytes.NewReader(data), test.out, func(val unsafe.Pointer, rb *avro.ResourceBank) error {
+				defer rb.Close()
+				v := *(*parallelRecord)(val)
+				// Copy the data out of the ResourceBank
+				v.Name = string([]byte(v.Name))
+				v.Tags = slices.Clone(v.Tags)
+				for i, tag := range v.Tags {
+					v.Tags[i] = string([]byte(tag))
+				}
+				got = append(got, v)
+				return nil
+			}, test.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			slices.SortFunc(got, func(a, b parallelRecord) int { return int(a.ID - b.ID) })
+			if diff := cmp.Diff(exp, got); diff != "" {
+				t.Fatalf("records not as expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadFileParallelOrder(t *testing.T) {
+	data, _ := writeParallelFile(t, 1000)
+
+	var last int64 = -1
+	if err := avro.ReadFileFor(bytes.NewReader(data), func(val *parallelRecord, rb *avro.ResourceBank) error {
+		defer rb.Close()
+		if val.ID != last+1 {
+			t.Fatalf("record %d follows %d", val.ID, last)
+		}
+		last = val.ID
+		return nil
+	}, avro.WithParallelism(8)); err != nil {
+		t.Fatal(err)
+	}
+	if last != 999 {
+		t.Fatalf("last record was %d", last)
+	}
+}
+
+func TestReadFileParallelErrors(t *testing.T) {
+	data, _ := writeParallelFile(t, 1000)
+
+	t.Run("callback", func(t *testing.T) {
+		stop := errors.New("stop")
+		var count int
+		err := avro.ReadFileFor(bytes.NewReader(data), func(val *parallelRecord, rb *avro.ResourceBank) error {
+			rb.Close()
+			count++
+			if count == 10 {
+				return stop
+			}
+			return nil
+		}, avro.WithParallelism(4))
+		if !errors.Is(err, stop) {
+			t.Fatalf("expected callback error, got %v", err)
+		}
+		if count != 10 {
+			t.Fatalf("callback called %d times after returning an error", count)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var count int
+		err := avro.ReadFileFor(bytes.NewReader(data[:len(data)-20]), func(val *parallelRecord, rb *avro.ResourceBank) error {
+			rb.Close()
+			count++
+			return nil
+		}, avro.WithParallelism(4))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if count == 0 || count >= 1000 {
+			t.Fatalf("read %d records before the error", count)
+		}
+	})
+}
+
+func TestReadFileParallelBadCount(t *testing.T) {
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[parallelRecord](&buf, avro.CompressionNull, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&parallelRecord{ID: 1, Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// The file ends with a single block: count, length, 6 bytes of record
+	// data and a 16 byte sync marker. Count and length each fit in a byte.
+	countOffset := len(data) - 16 - 6 - 2
+	if data[countOffset] != 2 || data[countOffset+1] != 12 {
+		t.Fatalf("unexpected block header %v", data[countOffset:countOffset+2])
+	}
+
+	tests := []struct {
+		name  string
+		count []byte
+	}{
+		{name: "negative", count: []byte{1}},
+		{name: "huge", count: binary.AppendVarint(nil, 1<<60)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bad := slices.Concat(data[:countOffset], test.count, data[countOffset+1:])
+			err := avro.ReadFileFor(bytes.NewReader(bad), func(val *parallelRecord, rb *avro.ResourceBank) error {
+				rb.Close()
+				return nil
+			}, avro.WithParallelism(2))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadFileParallelAllocs(t *testing.T) {
+	type record struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	const count = 10_000
+	var buf bytes.Buffer
+	enc, err := avro.NewEncoderFor[record](&buf, avro.CompressionNull, 1<<16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range count {
+		if err := enc.Encode(&record{ID: int64(i), Name: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// The application keeps every record, so nothing is reused. Each record
+	// should cost a few times its own size at most. If each record is
+	// allocated in its own ResourceBank it costs an array of 16 records.
+	kept := make([]*record, 0, count)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := avro.ReadFileFor(bytes.NewReader(data), func(val *record, rb *avro.ResourceBank) error {
+		kept = append(kept, val)
+		return nil
+	}, avro.WithParallelism(2)); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if len(kept) != count {
+		t.Fatalf("read %d records", len(kept))
+	}
+	perRecord := (after.TotalAlloc - before.TotalAlloc) / count
+	if perRecord > 300 {
+		t.Fatalf("%d bytes allocated per record", perRecord)
+	}
+}
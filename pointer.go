@@ -1,6 +1,7 @@
 package avro
 
 import (
+	"errors"
 	"reflect"
 	"unsafe"
 )
@@ -19,6 +20,8 @@ func (c *PointerCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
 
 var pointerType = reflect.TypeFor[unsafe.Pointer]()
 
+var errNilPointer = errors.New("nil pointer for a field that is not nullable")
+
 func (c *PointerCodec) New(r *ReadBuf) unsafe.Pointer {
 	return r.Alloc(pointerType)
 }
@@ -32,6 +35,7 @@ func (c *PointerCodec) Write(w *WriteBuf, p unsafe.Pointer) {
 	// need to worry about writing the union selector.
 	pp := *(*unsafe.Pointer)(p)
 	if pp == nil {
+		w.SetError(errNilPointer)
 		return
 	}
 	c.Codec.Write(w, pp)
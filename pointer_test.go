@@ -79,3 +79,13 @@ func TestPointerCodec(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestPointerCodecWriteNil(t *testing.T) {
+	c := PointerCodec{Codec: StringCodec{}}
+	var p *string
+	w := NewWriteBuf(nil)
+	c.Write(w, unsafe.Pointer(&p))
+	if w.Err() == nil {
+		t.Fatal("expected an error writing a nil pointer")
+	}
+}
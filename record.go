@@ -1,6 +1,7 @@
 package avro
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
@@ -11,9 +12,37 @@ type recordCodecField struct {
 	// Codec for this field
 	codec Codec
 	// offset of this field within the struct representing the record. -1 if this
-	// field is not in the struct and therefore should be skipped
+	// field is not in the struct and therefore should be skipped when reading
 	offset uintptr
 	name   string
+	// fill is the encoded value written for a field that is not in the
+	// struct. fillErr is set instead if there is no value we can write.
+	fill    []byte
+	fillErr error
+}
+
+// unmappedFieldValue returns the AVRO encoding of the value to write for a
+// schema field that has no matching struct field. This is the field's default,
+// or null if the field is nullable.
+func unmappedFieldValue(f SchemaRecordField) ([]byte, error) {
+	if len(f.Default) != 0 {
+		data, err := encodeDefault(f.Type, f.Default)
+		if err != nil {
+			return nil, fmt.Errorf("encoding default: %w", err)
+		}
+		return data, nil
+	}
+	switch f.Type.Type {
+	case "null":
+		return nil, nil
+	case "union":
+		for i, s := range f.Type.Union {
+			if s.Type == "null" {
+				return binary.AppendVarint(nil, int64(i)), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("field has no default and is not nullable")
 }
 
 type recordCodec struct {
@@ -55,7 +84,113 @@ func (rc *recordCodec) Omit(p unsafe.Pointer) bool {
 
 func (rc *recordCodec) Write(w *WriteBuf, p unsafe.Pointer) {
 	for _, rf := range rc.fields {
+		if rf.offset == math.MaxUint64 {
+			if rf.fillErr != nil {
+				w.SetError(fmt.Errorf("no struct field to write: %w", rf.fillErr))
+				w.addErrorPath(rf.name)
+				return
+			}
+			w.Write(rf.fill)
+			continue
+		}
 		fp := unsafe.Add(p, rf.offset)
 		rf.codec.Write(w, fp)
+		if w.err != nil {
+			w.addErrorPath(rf.name)
+			return
+		}
+	}
+}
+
+// recordMapCodec decodes a record into a map with string keys. Each field of the
+// record becomes an entry in the map.
+type recordMapCodec struct {
+	rtype     reflect.Type
+	valueType reflect.Type
+	fields    []recordCodecField
+}
+
+func (b *codecBuilder) buildRecordMapCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	if typ.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("map for a record must have string keys, not %s", typ.Key())
+	}
+
+	rc := &recordMapCodec{
+		rtype:     typ,
+		valueType: typ.Elem(),
+	}
+	b.records[recordKey{object: schema.Object, typ: typ}] = rc
+	for _, schemaf := range schema.Object.Fields {
+		codec, err := b.build(schemaf.Type, typ.Elem(), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get codec for field %q: %w", schemaf.Name, err)
+		}
+		rc.fields = append(rc.fields, recordCodecField{
+			codec: codec,
+			name:  schemaf.Name,
+		})
+	}
+	return rc, nil
+}
+
+func (rc *recordMapCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	// p is a pointer to a map pointer
+	mp := *(*unsafe.Pointer)(p)
+	if mp == nil {
+		mp = reflect.MakeMapWithSize(rc.rtype, len(rc.fields)).UnsafePointer()
+		*(*unsafe.Pointer)(p) = mp
+	}
+
+	for i, f := range rc.fields {
+		// Writer fields the reader doesn't have are skipped rather than added
+		// to the map.
+		if f.offset == math.MaxUint64 {
+			if err := f.codec.Skip(r); err != nil {
+				return fmt.Errorf("failed to skip field %d %q of record. %w", i, f.name, err)
+			}
+			continue
+		}
+		val := r.Alloc(rc.valueType)
+		if err := f.codec.Read(r, val); err != nil {
+			return fmt.Errorf("failed reading field %d %q of record. %w", i, f.name, err)
+		}
+		key := f.name
+		mapassign(unpackEFace(rc.rtype).data, mp, unsafe.Pointer(&key), val)
+	}
+	return nil
+}
+
+func (rc *recordMapCodec) Skip(r *ReadBuf) error {
+	for i, f := range rc.fields {
+		if err := f.codec.Skip(r); err != nil {
+			return fmt.Errorf("failed to skip field %d %q of record. %w", i, f.name, err)
+		}
+	}
+	return nil
+}
+
+func (rc *recordMapCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(rc.rtype)
+}
+
+func (rc *recordMapCodec) Omit(p unsafe.Pointer) bool {
+	return false
+}
+
+func (rc *recordMapCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	m := reflect.NewAt(rc.rtype, p).Elem()
+	val := reflect.New(rc.valueType)
+	for _, f := range rc.fields {
+		v := m.MapIndex(reflect.ValueOf(f.name).Convert(rc.rtype.Key()))
+		if !v.IsValid() {
+			w.SetError(fmt.Errorf("map has no entry for record field %q", f.name))
+			return
+		}
+		val.Elem().Set(v)
+		f.codec.Write(w, val.UnsafePointer())
+		if w.err != nil {
+			w.addErrorPath(f.name)
+			return
+		}
 	}
 }
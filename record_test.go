@@ -1,6 +1,7 @@
 package avro
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"unsafe"
@@ -42,7 +43,7 @@ func TestRecordCodec(t *testing.T) {
 	}
 
 	var r record
-	c, err := buildRecordCodec(schema, reflect.TypeOf(r))
+	c, err := buildCodec(schema, reflect.TypeOf(r), false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -137,3 +138,247 @@ func TestRecordRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordCodecTagDefaults(t *testing.T) {
+	type record struct {
+		Name  string            `json:"name"`
+		Hat   string            `json:"hat" avro:"default=cat"`
+		Count *int32            `json:"count" avro:"default=7"`
+		Tags  map[string]string `json:"tags" avro:"default={\"a\":\"b\"}"`
+		None  string            `json:"none"`
+	}
+
+	schema := Schema{
+		Type: "record",
+		Object: &SchemaObject{
+			Name: "Record",
+			Fields: []SchemaRecordField{
+				{Name: "name", Type: Schema{Type: "string"}},
+			},
+		},
+	}
+
+	data := []byte{6, 'j', 'i', 'm'}
+
+	c, err := buildCodec(schema, reflect.TypeFor[record](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r record
+	buf := NewReadBuf(data)
+	if err := c.Read(buf, unsafe.Pointer(&r)); err != nil {
+		t.Fatal(err)
+	}
+	count := int32(7)
+	exp := record{Name: "jim", Hat: "cat", Count: &count, Tags: map[string]string{"a": "b"}}
+	if diff := cmp.Diff(exp, r); diff != "" {
+		t.Fatalf("record differs. %s", diff)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unread data (%d)", buf.Len())
+	}
+
+	// Defaults are not written, as they aren't in the schema
+	var w WriteBuf
+	c.Write(&w, unsafe.Pointer(&r))
+	if diff := cmp.Diff(data, w.Bytes()); diff != "" {
+		t.Fatalf("written data differs. %s", diff)
+	}
+
+	buf.Reset(data)
+	if err := c.Skip(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unread data (%d)", buf.Len())
+	}
+}
+
+func TestRecordCodecAliases(t *testing.T) {
+	type record struct {
+		Name  string `json:"name"`
+		Hat   string `json:"hat" avro:"alias=cap|bonnet"`
+		Shoe  string `json:"shoe"`
+		Other string `json:"other"`
+	}
+
+	schema := Schema{
+		Type: "record",
+		Object: &SchemaObject{
+			Name: "Record",
+			Fields: []SchemaRecordField{
+				// Matched via the schema's aliases
+				{Name: "fullName", Type: Schema{Type: "string"}, Aliases: []string{"name"}},
+				// Matched via the struct tag aliases
+				{Name: "bonnet", Type: Schema{Type: "string"}},
+				// Exact names beat aliases
+				{Name: "footwear", Type: Schema{Type: "string"}, Aliases: []string{"other"}},
+				{Name: "other", Type: Schema{Type: "string"}},
+				{Name: "boot", Type: Schema{Type: "string"}, Aliases: []string{"shoe"}},
+			},
+		},
+	}
+
+	data := []byte{
+		6, 'j', 'i', 'm',
+		6, 'c', 'a', 't',
+		2, 'a',
+		2, 'b',
+		2, 'c',
+	}
+
+	c, err := buildCodec(schema, reflect.TypeFor[record](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r record
+	buf := NewReadBuf(data)
+	if err := c.Read(buf, unsafe.Pointer(&r)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(record{Name: "jim", Hat: "cat", Shoe: "c", Other: "b"}, r); diff != "" {
+		t.Fatalf("record differs. %s", diff)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unread data (%d)", buf.Len())
+	}
+}
+
+type listNode struct {
+	Value int64     `json:"value"`
+	Next  *listNode `json:"next"`
+}
+
+type treeNode struct {
+	Name     string     `json:"name"`
+	Children []treeNode `json:"children"`
+}
+
+func TestRecordCodecRecursive(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		in     any
+	}{
+		{
+			name: "linked list",
+			schema: `{"type": "record", "name": "Node", "namespace": "com.acme", "fields": [
+				{"name": "value", "type": "long"},
+				{"name": "next", "type": ["null", "Node"]}
+			]}`,
+			in: &listNode{Value: 1, Next: &listNode{Value: 2, Next: &listNode{Value: 3}}},
+		},
+		{
+			name: "tree",
+			schema: `{"type": "record", "name": "Tree", "namespace": "com.acme", "fields": [
+				{"name": "name", "type": "string"},
+				{"name": "children", "type": {"type": "array", "items": "com.acme.Tree"}}
+			]}`,
+			in: &treeNode{Name: "root", Children: []treeNode{
+				{Name: "a", Children: []treeNode{{Name: "a1"}, {Name: "a2"}}},
+				{Name: "b"},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := SchemaFromString(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := reflect.ValueOf(test.in)
+			typ := in.Type().Elem()
+			c, err := buildCodec(schema, typ, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var w WriteBuf
+			c.Write(&w, in.UnsafePointer())
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			buf := NewReadBuf(w.Bytes())
+			out := reflect.New(typ)
+			if err := c.Read(buf, out.UnsafePointer()); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.in, out.Interface(), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("record differs. %s", diff)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("unread data (%d)", buf.Len())
+			}
+
+			// Skipping uses a codec built without a type
+			skip, err := buildCodec(schema, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.Reset(w.Bytes())
+			if err := skip.Skip(buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("unread data after skip (%d)", buf.Len())
+			}
+
+			// Recursive records can also be decoded into maps
+			anyCodec, err := buildCodec(schema, reflect.TypeFor[map[string]any](), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m map[string]any
+			buf.Reset(w.Bytes())
+			if err := anyCodec.Read(buf, unsafe.Pointer(&m)); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("unread data after reading into a map (%d)", buf.Len())
+			}
+		})
+	}
+}
+
+func TestRecordCodecWriteUnmapped(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+
+	schema := Schema{
+		Type: "record",
+		Object: &SchemaObject{
+			Name: "Record",
+			Fields: []SchemaRecordField{
+				{Name: "name", Type: Schema{Type: "string"}},
+				{Name: "count", Type: Schema{Type: "long"}},
+			},
+		},
+	}
+
+	// A codec built for reading can be built with fields missing from the
+	// struct, but can't write them.
+	c, err := buildCodec(schema, reflect.TypeFor[record](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriteBuf(nil)
+	c.Write(w, unsafe.Pointer(&record{Name: "jim"}))
+	var ee *EncodeError
+	if !errors.As(w.Err(), &ee) {
+		t.Fatalf("expected an EncodeError, got %v", w.Err())
+	}
+	if path := ee.Path(); path != "count" {
+		t.Fatalf("path is %q", path)
+	}
+
+	// A codec built for writing fails when it is built.
+	if _, err := buildWriteCodec(schema, reflect.TypeFor[record]()); err == nil {
+		t.Fatal("expected an error building a write codec")
+	}
+}
@@ -0,0 +1,521 @@
+package avro
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// buildResolvedCodec builds a codec that reads data written with the writer
+// schema into typ, where typ is described by the reader schema. It follows the
+// schema resolution rules in the AVRO specification. As with buildCodec, typ
+// can be nil, in which case the codec is only used to skip over data.
+func buildResolvedCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	writer, err := resolveNames(writer)
+	if err != nil {
+		return nil, fmt.Errorf("writer schema: %w", err)
+	}
+	reader, err = resolveNames(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reader schema: %w", err)
+	}
+	return newCodecBuilder().buildResolved(writer, reader, typ, omit)
+}
+
+func (b *codecBuilder) buildResolved(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	if typ == nil {
+		return b.build(writer, nil, false)
+	}
+
+	if writer.Type == "union" {
+		return b.buildResolvedWriterUnionCodec(writer, reader, typ, omit)
+	}
+	if reader.Type == "union" {
+		return b.buildResolvedReaderUnionCodec(writer, reader, typ, omit)
+	}
+
+	if !schemasMatch(writer, reader) {
+		return nil, fmt.Errorf("writer schema %s does not match reader schema %s", writer.Type, reader.Type)
+	}
+
+	if writer.Type != "null" {
+		if typ.Kind() == reflect.Pointer {
+			c, err := b.buildResolved(writer, reader, typ.Elem(), false)
+			if err != nil {
+				return nil, err
+			}
+			return &PointerCodec{Codec: c}, nil
+		}
+
+		// Custom codecs understand the wire format, so are given the writer
+		// schema.
+		registryMutex.RLock()
+		cf, ok := registry[typ]
+		registryMutex.RUnlock()
+		if ok {
+			return cf(writer, typ, omit)
+		}
+
+		if typ.Kind() == reflect.Interface {
+			natural, err := naturalType(reader)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkNaturalImplements(reader, typ, natural); err != nil {
+				return nil, err
+			}
+			c, err := b.buildResolved(writer, reader, natural, false)
+			if err != nil {
+				return nil, err
+			}
+			return newInterfaceCodec(reader, typ, natural, c), nil
+		}
+	}
+
+	switch writer.Type {
+	case "record":
+		return b.buildResolvedRecordCodec(writer, reader, typ)
+	case "enum":
+		return b.buildResolvedEnumCodec(writer, reader, typ, omit)
+	case "array":
+		return b.buildResolvedArrayCodec(writer, reader, typ, omit)
+	case "map":
+		return b.buildResolvedMapCodec(writer, reader, typ, omit)
+	case "int", "long":
+		switch reader.Type {
+		case "float", "double":
+			return buildPromotedIntCodec(typ, omit)
+		}
+	case "float":
+		if reader.Type == "double" {
+			return buildPromotedFloatCodec(typ, omit)
+		}
+	case "string", "bytes":
+		// Strings and bytes have the same wire format, so choose the codec
+		// that suits the Go type.
+		if reader.Type != writer.Type {
+			if c, err := b.build(reader, typ, omit); err == nil {
+				return c, nil
+			}
+			return b.build(writer, typ, omit)
+		}
+	}
+
+	// Everything else has the same wire format for reader and writer, so we
+	// can build a codec for the reader.
+	return b.build(reader, typ, omit)
+}
+
+// schemasMatch determines whether data written with the writer schema can be
+// read with the reader schema. Neither schema is a union. Unlike the AVRO
+// specification we don't insist that the names of records and enums match, as
+// schemas derived from Go types won't have the same names as schemas from
+// elsewhere. Names are used to choose between the branches of a union.
+func schemasMatch(writer, reader Schema) bool {
+	if writer.Type == reader.Type {
+		if writer.Type == "fixed" {
+			return writer.Object != nil && reader.Object != nil && writer.Object.Size == reader.Object.Size
+		}
+		return true
+	}
+
+	switch writer.Type {
+	case "int":
+		return reader.Type == "long" || reader.Type == "float" || reader.Type == "double"
+	case "long":
+		return reader.Type == "float" || reader.Type == "double"
+	case "float":
+		return reader.Type == "double"
+	case "string":
+		return reader.Type == "bytes"
+	case "bytes":
+		return reader.Type == "string"
+	}
+	return false
+}
+
+// schemaNamesMatch returns true if both schemas are named types with the same
+// unqualified name.
+func schemaNamesMatch(writer, reader Schema) bool {
+	if writer.Object == nil || reader.Object == nil {
+		return false
+	}
+	return unqualifiedName(writer.Object.Name) == unqualifiedName(reader.Object.Name)
+}
+
+func unqualifiedName(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// matchReaderUnionBranch finds the branch of the reader union to use for data
+// written with the writer schema. We prefer a branch of the same type (with
+// the same name for named types), then fall back to the first branch that
+// matches according to the resolution rules.
+func matchReaderUnionBranch(writer Schema, union []Schema) (int, bool) {
+	for i, r := range union {
+		if r.Type != writer.Type {
+			continue
+		}
+		switch r.Type {
+		case "record", "enum", "fixed":
+			if schemaNamesMatch(writer, r) && schemasMatch(writer, r) {
+				return i, true
+			}
+		default:
+			return i, true
+		}
+	}
+	for i, r := range union {
+		if r.Type != "union" && schemasMatch(writer, r) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (b *codecBuilder) buildResolvedReaderUnionCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	index, ok := matchReaderUnionBranch(writer, reader.Union)
+	if !ok {
+		return nil, fmt.Errorf("no branch of reader union matches writer schema %s", writer.Type)
+	}
+	branch := reader.Union[index]
+
+	if isUnionStruct(typ) {
+		name := unionBranchName(branch)
+		for i := range typ.NumField() {
+			sf := typ.Field(i)
+			if tag, ok := avroTagOption(sf, "branch"); ok && (tag == name || branch.Object != nil && tag == branch.Object.FullName()) {
+				c, err := b.buildResolved(writer, branch, sf.Type, false)
+				if err != nil {
+					return nil, err
+				}
+				return &fieldCodec{Codec: c, offset: sf.Offset}, nil
+			}
+		}
+		return b.build(writer, nil, false)
+	}
+
+	return b.buildResolved(writer, branch, typ, omit)
+}
+
+func (b *codecBuilder) buildResolvedWriterUnionCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	c := unionCodec{codecs: make([]Codec, len(writer.Union))}
+	for i, w := range writer.Union {
+		sc, err := b.buildResolved(w, reader, typ, omit)
+		if err != nil {
+			// It's only an error if this branch actually appears in the data.
+			skip, serr := b.build(w, nil, false)
+			if serr != nil {
+				return nil, fmt.Errorf("failed to build union sub-codec %q: %w", w.Type, serr)
+			}
+			sc = &unresolvedCodec{Codec: skip, err: err}
+		}
+		c.codecs[i] = sc
+	}
+
+	// Use the faster codec for the common case of a value or null.
+	if len(c.codecs) == 2 {
+		for nonNull := range 2 {
+			if _, ok := c.codecs[1-nonNull].(nullCodec); ok {
+				if _, ok := c.codecs[nonNull].(nullCodec); !ok {
+					return &unionOneAndNullCodec{codec: c.codecs[nonNull], nonNull: uint8(nonNull)}, nil
+				}
+			}
+		}
+	}
+	return &c, nil
+}
+
+func (b *codecBuilder) buildResolvedRecordCodec(writer, reader Schema, typ reflect.Type) (_ Codec, err error) {
+	if writer.Object == nil || reader.Object == nil {
+		return nil, fmt.Errorf("record schema does not have object")
+	}
+
+	// Recursive records re-use the codec we're building
+	key := resolvedRecordKey{writer: writer.Object, reader: reader.Object, typ: typ}
+	if c, ok := b.resolved[key]; ok {
+		return c, nil
+	}
+
+	readerFields := make(map[string]SchemaRecordField, len(reader.Object.Fields))
+	readerAliases := make(map[string]string)
+	for _, f := range reader.Object.Fields {
+		readerFields[f.Name] = f
+		for _, alias := range f.Aliases {
+			readerAliases[alias] = f.Name
+		}
+	}
+
+	var ntf map[string]reflect.StructField
+	switch typ.Kind() {
+	case reflect.Struct:
+		ntf = make(map[string]reflect.StructField, typ.NumField())
+		for i := range typ.NumField() {
+			sf := typ.Field(i)
+			if name := nameForField(sf); name != "-" {
+				ntf[name] = sf
+			}
+		}
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map for a record must have string keys, not %s", typ.Key())
+		}
+	default:
+		return nil, fmt.Errorf("type for a record must be struct, not %s", typ.Kind())
+	}
+
+	var fields *[]recordCodecField
+	if ntf == nil {
+		rc := &recordMapCodec{rtype: typ, valueType: typ.Elem()}
+		b.resolved[key], fields = rc, &rc.fields
+	} else {
+		rc := &recordCodec{rtype: typ}
+		b.resolved[key], fields = rc, &rc.fields
+	}
+	defer func() {
+		if err != nil {
+			delete(b.resolved, key)
+		}
+	}()
+
+	writerNames := make(map[string]bool, len(writer.Object.Fields))
+	for _, wf := range writer.Object.Fields {
+		writerNames[wf.Name] = true
+	}
+
+	for _, wf := range writer.Object.Fields {
+		offset := uintptr(math.MaxUint64)
+		var fieldType reflect.Type
+		var omit bool
+		// Reader fields match writer fields by name, then by the reader's
+		// aliases.
+		rf, ok := readerFields[wf.Name]
+		if !ok && !writerNames[readerAliases[wf.Name]] {
+			rf, ok = readerFields[readerAliases[wf.Name]]
+		}
+		if ok {
+			delete(readerFields, rf.Name)
+			if ntf == nil {
+				// Decoding into a map
+				offset = 0
+				fieldType = typ.Elem()
+			} else if sf, ok := ntf[rf.Name]; ok {
+				offset = sf.Offset
+				fieldType = sf.Type
+				omit = omitEmpty(sf)
+			}
+		}
+
+		codec, err := b.buildResolved(wf.Type, rf.Type, fieldType, omit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get codec for field %q: %w", wf.Name, err)
+		}
+		*fields = append(*fields, recordCodecField{
+			codec:  codec,
+			offset: offset,
+			name:   wf.Name,
+		})
+	}
+
+	// Reader fields that aren't in the writer schema take their default
+	for _, rf := range reader.Object.Fields {
+		if _, ok := readerFields[rf.Name]; !ok {
+			continue
+		}
+		if len(rf.Default) == 0 {
+			return nil, fmt.Errorf("reader field %q is not in the writer schema and has no default", rf.Name)
+		}
+		var offset uintptr
+		var fieldType reflect.Type
+		if ntf == nil {
+			fieldType = typ.Elem()
+		} else if sf, ok := ntf[rf.Name]; ok {
+			offset = sf.Offset
+			fieldType = sf.Type
+		} else {
+			continue
+		}
+		codec, err := b.buildDefaultCodec(rf.Type, rf.Default, fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for field %q: %w", rf.Name, err)
+		}
+		*fields = append(*fields, recordCodecField{
+			codec:  codec,
+			offset: offset,
+			name:   rf.Name,
+		})
+	}
+
+	return b.resolved[key], nil
+}
+
+// resolvedEnumCodec reads an enum written with the writer's symbols and maps it
+// to the reader's symbols.
+type resolvedEnumCodec struct {
+	Codec
+	writer enumSymbols
+	// For each writer symbol, the index of the symbol in the reader's enum. -1
+	// if the reader has no such symbol and no default.
+	mapping []int
+}
+
+func (b *codecBuilder) buildResolvedEnumCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	ws, err := newEnumSymbols(writer)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := newEnumSymbols(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	def := -1
+	if reader.Object.Default != "" {
+		var ok bool
+		def, ok = rs.index[reader.Object.Default]
+		if !ok {
+			return nil, fmt.Errorf("enum default %q is not a symbol of enum %s", reader.Object.Default, rs.name)
+		}
+	}
+
+	c, err := buildEnumCodec(reader, typ, omit)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := resolvedEnumCodec{Codec: c, writer: ws, mapping: make([]int, len(ws.symbols))}
+	for i, sym := range ws.symbols {
+		index, ok := rs.index[sym]
+		if !ok {
+			index = def
+		}
+		rc.mapping[i] = index
+	}
+	return &rc, nil
+}
+
+func (c *resolvedEnumCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := c.writer.readIndex(r)
+	if err != nil {
+		return err
+	}
+	ri := c.mapping[index]
+	if ri < 0 {
+		return fmt.Errorf("symbol %q is not in the reader's enum and there is no default", c.writer.symbols[index])
+	}
+	return c.Codec.(enumSetter).setIndex(p, ri)
+}
+
+func (c *resolvedEnumCodec) Skip(r *ReadBuf) error {
+	return c.writer.Skip(r)
+}
+
+func (b *codecBuilder) buildResolvedArrayCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	if typ.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("type for an array must be a slice, not %s", typ)
+	}
+	itemCodec, err := b.buildResolved(writer.Object.Items, reader.Object.Items, typ.Elem(), false)
+	if err != nil {
+		return nil, fmt.Errorf("could not build array item codec: %w", err)
+	}
+	return &arrayCodec{itemCodec: itemCodec, itemType: typ.Elem(), omitEmpty: omit}, nil
+}
+
+func (b *codecBuilder) buildResolvedMapCodec(writer, reader Schema, typ reflect.Type, omit bool) (Codec, error) {
+	if typ.Kind() != reflect.Map || typ.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("type for a map must be a map with string keys")
+	}
+	valueCodec, err := b.buildResolved(writer.Object.Values, reader.Object.Values, typ.Elem(), false)
+	if err != nil {
+		return nil, fmt.Errorf("could not build map value codec: %w", err)
+	}
+	return &MapCodec{valueCodec: valueCodec, rtype: typ, omitEmpty: omit}, nil
+}
+
+// unresolvedCodec stands in for a branch of a writer union that can't be read
+// with the reader schema. It's an error if the branch is present in the data,
+// but it can be skipped.
+type unresolvedCodec struct {
+	Codec
+	err error
+}
+
+func (c *unresolvedCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	return c.err
+}
+
+// fieldCodec reads a value into a field at an offset within a struct.
+type fieldCodec struct {
+	Codec
+	offset uintptr
+}
+
+func (c *fieldCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	return c.Codec.Read(r, unsafe.Add(p, c.offset))
+}
+
+func (c *fieldCodec) Omit(p unsafe.Pointer) bool {
+	return c.Codec.Omit(unsafe.Add(p, c.offset))
+}
+
+func (c *fieldCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	c.Codec.Write(w, unsafe.Add(p, c.offset))
+}
+
+func buildPromotedIntCodec(typ reflect.Type, omit bool) (Codec, error) {
+	switch typ.Kind() {
+	case reflect.Float32:
+		return promotedIntCodec[float32]{floatCodec[float32]{omitEmpty: omit}}, nil
+	case reflect.Float64:
+		return promotedIntCodec[float64]{floatCodec[float64]{omitEmpty: omit}}, nil
+	}
+	return nil, fmt.Errorf("type %s not supported for an integer promoted to a float", typ)
+}
+
+// promotedIntCodec reads an int or long written by the writer into a float
+type promotedIntCodec[T float32 | float64] struct{ floatCodec[T] }
+
+func (promotedIntCodec[T]) Read(r *ReadBuf, p unsafe.Pointer) error {
+	i, err := r.Varint()
+	if err != nil {
+		return err
+	}
+	*(*T)(p) = T(i)
+	return nil
+}
+
+func (promotedIntCodec[T]) Skip(r *ReadBuf) error {
+	_, err := r.Varint()
+	return err
+}
+
+func buildPromotedFloatCodec(typ reflect.Type, omit bool) (Codec, error) {
+	switch typ.Kind() {
+	case reflect.Float32:
+		return FloatCodec{omitEmpty: omit}, nil
+	case reflect.Float64:
+		return promotedFloatCodec{DoubleCodec: DoubleCodec{omitEmpty: omit}}, nil
+	}
+	return nil, fmt.Errorf("type %s not supported for a float promoted to a double", typ)
+}
+
+// promotedFloatCodec reads a float written by the writer into a float64
+type promotedFloatCodec struct{ DoubleCodec }
+
+func (promotedFloatCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	var f float32
+	if err := (FloatCodec{}).Read(r, unsafe.Pointer(&f)); err != nil {
+		return err
+	}
+	*(*float64)(p) = float64(f)
+	return nil
+}
+
+func (promotedFloatCodec) Skip(r *ReadBuf) error {
+	return skip(r, 4)
+}
@@ -0,0 +1,458 @@
+package avro
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/go-json-experiment/json/jsontext"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolvedCodecRecord(t *testing.T) {
+	writer, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "a", "type": "int"},
+			{"name": "b", "type": "string"},
+			{"name": "gone", "type": {"type": "array", "items": "long"}},
+			{"name": "c", "type": "float"},
+			{"name": "e", "type": {"type": "enum", "name": "letters", "symbols": ["A", "B", "C"]}},
+			{"name": "u", "type": ["null", "int"]}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaFromString(`{
+		"type": "record",
+		"name": "com.example.thing",
+		"fields": [
+			{"name": "e", "type": {"type": "enum", "name": "letters", "symbols": ["X", "B", "A"], "default": "X"}},
+			{"name": "u", "type": ["null", "double"]},
+			{"name": "c", "type": "double"},
+			{"name": "b", "type": "bytes"},
+			{"name": "a", "type": "long"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type writeType struct {
+		A    int32   `json:"a"`
+		B    string  `json:"b"`
+		Gone []int64 `json:"gone"`
+		C    float32 `json:"c"`
+		E    string  `json:"e"`
+		U    *int32  `json:"u"`
+	}
+	type readType struct {
+		E string   `json:"e"`
+		U *float64 `json:"u"`
+		C float64  `json:"c"`
+		B []byte   `json:"b"`
+		A int64    `json:"a"`
+	}
+
+	wc, err := writer.Codec(writeType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := writer.ResolvedCodec(reader, readType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := int32(7)
+	tests := []struct {
+		name string
+		in   writeType
+		exp  readType
+	}{
+		{
+			name: "values",
+			in:   writeType{A: 1, B: "hat", Gone: []int64{1, 2}, C: 1.5, E: "A", U: &u},
+			exp:  readType{A: 1, B: []byte("hat"), C: 1.5, E: "A", U: ptr(7.0)},
+		},
+		{
+			name: "enum default",
+			in:   writeType{A: -3, B: "", C: -2, E: "C"},
+			exp:  readType{A: -3, C: -2, E: "X"},
+		},
+		{
+			name: "enum same symbol",
+			in:   writeType{E: "B"},
+			exp:  readType{E: "B"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var w WriteBuf
+			wc.Write(&w, unsafe.Pointer(&test.in))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewReadBuf(w.Bytes())
+			var actual readType
+			if err := rc.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, actual); diff != "" {
+				t.Fatalf("result not as expected. %s", diff)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+
+			r = NewReadBuf(w.Bytes())
+			if err := rc.Skip(r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread after skip", r.Len())
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+type stringOrLong struct {
+	Null bool    `avro:"branch=null"`
+	Str  *string `avro:"branch=string"`
+	Long *int64  `avro:"branch=long"`
+}
+
+func TestResolvedCodecUnions(t *testing.T) {
+	writer, err := SchemaFromString(`{
+		"type": "record",
+		"name": "outer",
+		"fields": [
+			{"name": "a", "type": ["null", "string", "long"]},
+			{"name": "b", "type": "int"},
+			{"name": "c", "type": {"type": "record", "name": "com.example.thing", "fields": [{"name": "a", "type": "long"}]}}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaFromString(`{
+		"type": "record",
+		"name": "outer",
+		"fields": [
+			{"name": "a", "type": ["null", "string"]},
+			{"name": "b", "type": ["null", "string", "long"]},
+			{"name": "c", "type": ["null", {"type": "record", "name": "other", "fields": [{"name": "a", "type": "long"}]}, {"type": "record", "name": "thing", "fields": [{"name": "a", "type": "long"}]}]}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type writeThing struct {
+		A int64 `json:"a"`
+	}
+	type writeType struct {
+		A stringOrLong `json:"a"`
+		B int32        `json:"b"`
+		C writeThing   `json:"c"`
+	}
+	type readType struct {
+		A string `json:"a"`
+		B *int64 `json:"b"`
+		C any    `json:"c"`
+	}
+
+	wc, err := writer.Codec(writeType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := writer.ResolvedCodec(reader, readType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(t *testing.T, in writeType) []byte {
+		var w WriteBuf
+		wc.Write(&w, unsafe.Pointer(&in))
+		if err := w.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return w.Bytes()
+	}
+
+	t.Run("resolvable", func(t *testing.T) {
+		r := NewReadBuf(write(t, writeType{A: stringOrLong{Str: ptr("hello")}, B: 37, C: writeThing{A: 12}}))
+		var actual readType
+		if err := rc.Read(r, unsafe.Pointer(&actual)); err != nil {
+			t.Fatal(err)
+		}
+		exp := readType{A: "hello", B: ptr[int64](37), C: map[string]any{"a": int64(12)}}
+		if diff := cmp.Diff(exp, actual); diff != "" {
+			t.Fatalf("result not as expected. %s", diff)
+		}
+	})
+
+	t.Run("unresolvable branch", func(t *testing.T) {
+		data := write(t, writeType{A: stringOrLong{Long: ptr[int64](1)}})
+		var actual readType
+		if err := rc.Read(NewReadBuf(data), unsafe.Pointer(&actual)); err == nil {
+			t.Fatal("expected an error reading a union branch the reader can't handle")
+		}
+		r := NewReadBuf(data)
+		if err := rc.Skip(r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Len() != 0 {
+			t.Fatalf("%d bytes unread after skip", r.Len())
+		}
+	})
+}
+
+func TestResolvedCodecErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		writer string
+		reader string
+		typ    reflect.Type
+	}{
+		{
+			name:   "missing field",
+			writer: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": "long"}]}`,
+			reader: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": "long"}, {"name": "B", "type": "long"}]}`,
+			typ:    reflect.TypeFor[struct{ A, B int64 }](),
+		},
+		{
+			name:   "demotion",
+			writer: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": "long"}]}`,
+			reader: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": "int"}]}`,
+			typ:    reflect.TypeFor[struct{ A int32 }](),
+		},
+		{
+			name:   "no union branch",
+			writer: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": "long"}]}`,
+			reader: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": ["null", "string"]}]}`,
+			typ:    reflect.TypeFor[struct{ A string }](),
+		},
+		{
+			name:   "fixed size",
+			writer: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": {"type": "fixed", "name": "f", "size": 4}}]}`,
+			reader: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": {"type": "fixed", "name": "f", "size": 8}}]}`,
+			typ:    reflect.TypeFor[struct{ A [8]byte }](),
+		},
+		{
+			name:   "bad enum default",
+			writer: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": {"type": "enum", "name": "e", "symbols": ["A"]}}]}`,
+			reader: `{"type": "record", "name": "a", "fields": [{"name": "A", "type": {"type": "enum", "name": "e", "symbols": ["B"], "default": "C"}}]}`,
+			typ:    reflect.TypeFor[struct{ A string }](),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer, err := SchemaFromString(test.writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader, err := SchemaFromString(test.reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buildResolvedCodec(writer, reader, test.typ, false); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestResolvedCodecEnumNoDefault(t *testing.T) {
+	writer := Schema{Type: "enum", Object: &SchemaObject{Name: "e", Symbols: []string{"A", "B"}}}
+	reader := Schema{Type: "enum", Object: &SchemaObject{Name: "e", Symbols: []string{"B"}}}
+
+	c, err := buildResolvedCodec(writer, reader, reflect.TypeFor[colour](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual colour
+	if err := c.Read(NewReadBuf([]byte{2}), unsafe.Pointer(&actual)); err != nil {
+		t.Fatal(err)
+	}
+	if actual != 0 {
+		t.Fatalf("expected index 0, got %d", actual)
+	}
+	if err := c.Read(NewReadBuf([]byte{0}), unsafe.Pointer(&actual)); err == nil {
+		t.Fatal("expected an error for a symbol not in the reader's enum")
+	}
+}
+
+func TestResolvedCodecMap(t *testing.T) {
+	writer, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "a", "type": "string"},
+			{"name": "b", "type": "long"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "b", "type": "long"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "a" is "hello", b is 7
+	data := []byte{10, 'h', 'e', 'l', 'l', 'o', 14}
+
+	t.Run("map", func(t *testing.T) {
+		c, err := buildResolvedCodec(writer, reader, reflect.TypeFor[map[string]int64](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewReadBuf(data)
+		var actual map[string]int64
+		if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]int64{"b": 7}, actual); diff != "" {
+			t.Fatalf("result not as expected. %s", diff)
+		}
+		if r.Len() != 0 {
+			t.Fatalf("%d bytes unread", r.Len())
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		c, err := buildResolvedCodec(writer, reader, reflect.TypeFor[any](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual any
+		if err := c.Read(NewReadBuf(data), unsafe.Pointer(&actual)); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]any{"b": int64(7)}, actual); diff != "" {
+			t.Fatalf("result not as expected. %s", diff)
+		}
+	})
+}
+
+func TestResolvedCodecDefaults(t *testing.T) {
+	writer, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "a", "type": "long"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "a", "type": "long"},
+			{"name": "b", "type": "string", "default": "hat"},
+			{"name": "c", "type": ["null", "long"], "default": null},
+			{"name": "d", "type": {"type": "array", "items": "double"}, "default": [1, 2.5]},
+			{"name": "e", "type": {"type": "enum", "name": "e", "symbols": ["X", "Y"]}, "default": "Y"},
+			{"name": "f", "type": "int", "default": 3}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type readType struct {
+		A int64     `json:"a"`
+		B string    `json:"b"`
+		C *int64    `json:"c"`
+		D []float64 `json:"d"`
+		E int       `json:"e"`
+	}
+
+	rc, err := writer.ResolvedCodec(reader, readType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{2}
+	r := NewReadBuf(data)
+	var actual readType
+	if err := rc.Read(r, unsafe.Pointer(&actual)); err != nil {
+		t.Fatal(err)
+	}
+	exp := readType{A: 1, B: "hat", D: []float64{1, 2.5}, E: 1}
+	if diff := cmp.Diff(exp, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("%d bytes unread", r.Len())
+	}
+
+	// Defaults must be valid for the field
+	reader.Object.Fields[1].Default = jsontext.Value("3")
+	if _, err := writer.ResolvedCodec(reader, readType{}); err == nil {
+		t.Fatal("expected an error for an invalid default")
+	}
+}
+
+func TestResolvedCodecAliases(t *testing.T) {
+	writer, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "old", "type": "long"},
+			{"name": "b", "type": "long"},
+			{"name": "c", "type": "long"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaFromString(`{
+		"type": "record",
+		"name": "thing",
+		"fields": [
+			{"name": "new", "type": "long", "aliases": ["old"]},
+			{"name": "c", "type": "long", "aliases": ["b"]}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type readType struct {
+		New int64 `json:"new"`
+		C   int64 `json:"c"`
+	}
+	rc, err := writer.ResolvedCodec(reader, readType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual readType
+	r := NewReadBuf([]byte{2, 4, 6})
+	if err := rc.Read(r, unsafe.Pointer(&actual)); err != nil {
+		t.Fatal(err)
+	}
+	// c matches the writer's c exactly, so the alias for b isn't used.
+	if diff := cmp.Diff(readType{New: 1, C: 3}, actual); diff != "" {
+		t.Fatalf("result not as expected. %s", diff)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("%d bytes unread", r.Len())
+	}
+}
@@ -0,0 +1,228 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// RollingEncoder writes records of type T to a sequence of AVRO files, starting
+// a new file once the current file reaches a size or row limit. Each file has
+// its own header, so each is a complete AVRO file. Set the limits with
+// WithMaxFileBytes and WithMaxFileRows. It is not safe for concurrent use.
+//
+//	enc, err := avro.NewRollingEncoder[myrecord](func(index int) (string, io.WriteCloser, error) {
+//	    name := fmt.Sprintf("out-%04d.avro", index)
+//	    f, err := os.Create(name)
+//	    return name, f, err
+//	}, avro.CompressionSnappy, 100_000, avro.WithMaxFileBytes(256<<20))
+//	...
+//	if err := enc.Close(); err != nil {
+//	    return err
+//	}
+//	for _, f := range enc.Files() {
+//	    ...
+//	}
+type RollingEncoder[T any] struct {
+	newFile         func(index int) (name string, w io.WriteCloser, err error)
+	schema          Schema
+	compression     Compression
+	approxBlockSize int
+	opts            []WriteOption
+	maxFileBytes    int64
+	maxFileRows     int64
+
+	enc     *Encoder[T]
+	current RollingFile
+	cw      *countingWriter
+	// next is the index to pass to newFile for the next file
+	next   int
+	files  []RollingFile
+	closed bool
+}
+
+// RollingFile describes a file written by a RollingEncoder.
+type RollingFile struct {
+	// Name is the name returned by the function that created the file.
+	Name string
+	// Rows is the number of records in the file.
+	Rows int64
+	// Bytes is the size of the file.
+	Bytes int64
+	// Err is set if the file could not be completed. The file is likely to be
+	// incomplete.
+	Err error
+}
+
+// WithMaxFileBytes causes a RollingEncoder to start a new file once the current
+// file is at least n bytes long. The size is checked after each block is
+// written, so files may be larger than n by up to a block. It is ignored by
+// other writers.
+func WithMaxFileBytes(n int64) WriteOption {
+	return func(wc *writeConfig) {
+		wc.maxFileBytes = n
+	}
+}
+
+// WithMaxFileRows causes a RollingEncoder to start a new file once the current
+// file has n rows. It is ignored by other writers.
+func WithMaxFileRows(n int64) WriteOption {
+	return func(wc *writeConfig) {
+		wc.maxFileRows = n
+	}
+}
+
+// NewRollingEncoder returns a RollingEncoder. newFile is called to create each
+// file. index is 0 for the first call and increases by one for each call.
+// newFile returns a name for the file, which is used in the list returned by
+// Files, and the writer for the file. The RollingEncoder closes the writer when
+// the file is complete. Files are only created when there is a record to write
+// to them. The other parameters are as for NewEncoderFor, and opts are passed
+// to the Encoder for each file.
+func NewRollingEncoder[T any](newFile func(index int) (name string, w io.WriteCloser, err error), compression Compression, approxBlockSize int, opts ...WriteOption) (*RollingEncoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	s, err := schemaForType(typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating schema: %w", err)
+	}
+
+	// Check we can create a FileWriter with these options before we try to
+	// create any files.
+	if _, err := buildWriteCodec(s, typ); err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+	if _, err := NewFileWriter(nil, compression, opts...); err != nil {
+		return nil, fmt.Errorf("creating file writer: %w", err)
+	}
+
+	wc := newWriteConfig(opts)
+	return &RollingEncoder[T]{
+		newFile:         newFile,
+		schema:          s,
+		compression:     compression,
+		approxBlockSize: approxBlockSize,
+		opts:            append(opts[:len(opts):len(opts)], WithCloseWriter()),
+		maxFileBytes:    wc.maxFileBytes,
+		maxFileRows:     wc.maxFileRows,
+	}, nil
+}
+
+// Encode writes a record. It starts a new file if there is no current file, and
+// completes the current file if the record takes it to one of the limits.
+func (r *RollingEncoder[T]) Encode(v *T) error {
+	if r.closed {
+		return errEncoderClosed
+	}
+	if r.enc == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+
+	// If the encoder fails to flush, the row is still buffered and will be
+	// written if a later flush succeeds, so we count it. Rows that can't be
+	// encoded are discarded.
+	buffered := r.enc.stats.Rows + int64(r.enc.count)
+	err := r.enc.Encode(v)
+	if r.enc.stats.Rows+int64(r.enc.count) > buffered {
+		r.current.Rows++
+	}
+	if err != nil {
+		return err
+	}
+
+	// The file only grows when the encoder writes a block, which leaves the
+	// encoder empty. We don't want to roll over before then as the header
+	// alone may exceed the size limit.
+	if (r.maxFileRows > 0 && r.current.Rows >= r.maxFileRows) ||
+		(r.maxFileBytes > 0 && r.enc.count == 0 && r.cw.n >= r.maxFileBytes) {
+		return r.finish()
+	}
+	return nil
+}
+
+// Flush writes any buffered records to the current file.
+func (r *RollingEncoder[T]) Flush() error {
+	if r.enc == nil {
+		return nil
+	}
+	return r.enc.Flush()
+}
+
+// Close completes the current file, if there is one. The RollingEncoder can't
+// be used after Close is called.
+func (r *RollingEncoder[T]) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.enc == nil {
+		return nil
+	}
+	return r.finish()
+}
+
+// Files returns the files that have been completed, in the order they were
+// created. The current file is not included until it is complete. Files that
+// could not be completed are included with Err set.
+func (r *RollingEncoder[T]) Files() []RollingFile {
+	return slices.Clone(r.files)
+}
+
+func (r *RollingEncoder[T]) open() error {
+	// We never reuse an index, even if newFile fails, as it may have created
+	// something.
+	index := r.next
+	r.next++
+	name, w, err := r.newFile(index)
+	if err != nil {
+		return fmt.Errorf("creating file %d: %w", index, err)
+	}
+	r.cw = &countingWriter{w: w}
+	enc, err := NewEncoderForSchema[T](r.cw, r.schema, r.compression, r.approxBlockSize, r.opts...)
+	if err != nil {
+		err = errors.Join(fmt.Errorf("creating encoder for %s: %w", name, err), w.Close())
+		r.files = append(r.files, RollingFile{Name: name, Bytes: r.cw.n, Err: err})
+		return err
+	}
+	r.enc = enc
+	r.current = RollingFile{Name: name}
+	return nil
+}
+
+// finish completes the current file. If that fails the file is still added to
+// the list of files, with the error.
+func (r *RollingEncoder[T]) finish() error {
+	enc := r.enc
+	r.enc = nil
+	err := enc.Close()
+	if err != nil {
+		err = fmt.Errorf("closing %s: %w", r.current.Name, err)
+	}
+	r.current.Bytes = r.cw.n
+	r.current.Err = err
+	r.files = append(r.files, r.current)
+	return err
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.WriteCloser
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) Close() error {
+	return c.w.Close()
+}
@@ -0,0 +1,200 @@
+package avro_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+type rollingRecord struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type rollingFile struct {
+	bytes.Buffer
+	closed bool
+	fail   bool
+}
+
+func (f *rollingFile) Write(p []byte) (int, error) {
+	if f.fail {
+		return 0, fmt.Errorf("disk full")
+	}
+	return f.Buffer.Write(p)
+}
+
+func (f *rollingFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestRollingEncoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []avro.WriteOption
+		rows      int
+		wantFiles []int64
+	}{
+		{name: "rows", opts: []avro.WriteOption{avro.WithMaxFileRows(4)}, rows: 10, wantFiles: []int64{4, 4, 2}},
+		{name: "rows exact", opts: []avro.WriteOption{avro.WithMaxFileRows(5)}, rows: 10, wantFiles: []int64{5, 5}},
+		{name: "bytes", opts: []avro.WriteOption{avro.WithMaxFileBytes(1), avro.WithMaxBlockRows(3)}, rows: 7, wantFiles: []int64{3, 3, 1}},
+		{name: "no limit", rows: 10, wantFiles: []int64{10}},
+		{name: "empty", rows: 0, wantFiles: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var files []*rollingFile
+			enc, err := avro.NewRollingEncoder[rollingRecord](func(index int) (string, io.WriteCloser, error) {
+				if index != len(files) {
+					t.Errorf("index %d, expected %d", index, len(files))
+				}
+				f := &rollingFile{}
+				files = append(files, f)
+				return fmt.Sprintf("file-%d", index), f, nil
+			}, avro.CompressionSnappy, 10_000, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var contents []rollingRecord
+			for i := range test.rows {
+				contents = append(contents, rollingRecord{ID: i, Name: fmt.Sprintf("name %d", i)})
+				if err := enc.Encode(&contents[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var wantFiles []avro.RollingFile
+			var actual []rollingRecord
+			for i, f := range files {
+				if !f.closed {
+					t.Errorf("file %d not closed", i)
+				}
+				wantFiles = append(wantFiles, avro.RollingFile{
+					Name:  fmt.Sprintf("file-%d", i),
+					Rows:  test.wantFiles[i],
+					Bytes: int64(f.Len()),
+				})
+				if err := avro.ReadFileFor(bufio.NewReader(f), func(val *rollingRecord, rb *avro.ResourceBank) error {
+					actual = append(actual, *val)
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(wantFiles, enc.Files()); diff != "" {
+				t.Errorf("files not as expected. %s", diff)
+			}
+			if diff := cmp.Diff(contents, actual); diff != "" {
+				t.Errorf("records not as expected. %s", diff)
+			}
+		})
+	}
+}
+
+func TestRollingEncoderFileError(t *testing.T) {
+	enc, err := avro.NewRollingEncoder[rollingRecord](func(index int) (string, io.WriteCloser, error) {
+		return "", nil, fmt.Errorf("no space")
+	}, avro.CompressionNull, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&rollingRecord{ID: 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestRollingEncoderFinishError(t *testing.T) {
+	var files []*rollingFile
+	var indexes []int
+	enc, err := avro.NewRollingEncoder[rollingRecord](func(index int) (string, io.WriteCloser, error) {
+		indexes = append(indexes, index)
+		f := &rollingFile{}
+		files = append(files, f)
+		return fmt.Sprintf("file-%d", index), f, nil
+	}, avro.CompressionNull, 10_000, avro.WithMaxFileRows(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Encode(&rollingRecord{ID: 0}); err != nil {
+		t.Fatal(err)
+	}
+	headerLen := int64(files[0].Len())
+	files[0].fail = true
+	if err := enc.Encode(&rollingRecord{ID: 1}); err == nil {
+		t.Fatal("expected an error completing the first file")
+	}
+	if !files[0].closed {
+		t.Error("failed file not closed")
+	}
+
+	if err := enc.Encode(&rollingRecord{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]int{0, 1}, indexes); diff != "" {
+		t.Errorf("indexes not as expected. %s", diff)
+	}
+	got := enc.Files()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 files, got %#v", got)
+	}
+	if got[0].Name != "file-0" || got[0].Rows != 2 || got[0].Bytes != headerLen || got[0].Err == nil {
+		t.Errorf("failed file not as expected: %#v", got[0])
+	}
+	if diff := cmp.Diff(avro.RollingFile{Name: "file-1", Rows: 1, Bytes: int64(files[1].Len())}, got[1]); diff != "" {
+		t.Errorf("second file not as expected. %s", diff)
+	}
+}
+
+func TestRollingEncoderFlushError(t *testing.T) {
+	var files []*rollingFile
+	enc, err := avro.NewRollingEncoder[rollingRecord](func(index int) (string, io.WriteCloser, error) {
+		f := &rollingFile{}
+		files = append(files, f)
+		return fmt.Sprintf("file-%d", index), f, nil
+	}, avro.CompressionNull, 10_000, avro.WithMaxBlockRows(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Encode(&rollingRecord{ID: 0}); err != nil {
+		t.Fatal(err)
+	}
+	files[0].fail = true
+	// The row can't be flushed but is still buffered in the file.
+	if err := enc.Encode(&rollingRecord{ID: 1}); err == nil {
+		t.Fatal("expected an error flushing the row")
+	}
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected an error completing the file")
+	}
+
+	got := enc.Files()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 file, got %#v", got)
+	}
+	if got[0].Rows != 2 || got[0].Err == nil {
+		t.Errorf("failed file not as expected: %#v", got[0])
+	}
+
+	// Changing the list we're given does not change the encoder's list.
+	got[0].Name = "changed"
+	if name := enc.Files()[0].Name; name != "file-0" {
+		t.Errorf("file name changed to %q", name)
+	}
+}
@@ -2,7 +2,10 @@ package avro
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/go-json-experiment/json"
 	"github.com/go-json-experiment/json/jsontext"
@@ -32,6 +35,26 @@ func (s Schema) Codec(out any) (Codec, error) {
 	return buildCodec(s, typ, false)
 }
 
+// ResolvedCodec creates a codec that decodes data written with schema s into
+// out, resolving s against the reader schema according to the AVRO schema
+// resolution rules. The reader schema should describe out. Fields in the reader
+// schema that are missing from s take their default, and it is an error if
+// they have none. Values are promoted where the reader schema has a wider type
+// (e.g. int to long, float to double).
+func (s Schema) ResolvedCodec(reader Schema, out any) (Codec, error) {
+	typ := reflect.TypeOf(out)
+	if typ != nil {
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("out must be a struct or pointer to a struct")
+		}
+	}
+
+	return buildResolvedCodec(s, reader, typ, false)
+}
+
 func (s *Schema) Marshal() ([]byte, error) {
 	return json.Marshal(s)
 }
@@ -61,12 +84,180 @@ type SchemaObject struct {
 	Size int `json:"size,omitempty"`
 	// The values of an enum
 	Symbols []string `json:"symbols,omitempty"`
+	// The symbol used when reading an enum value that's not in the reader's
+	// symbols. Only enums have a default: for other types a "default"
+	// attribute is kept in Props.
+	Default string `json:"-"`
+	// Documentation for a named type
+	Doc string `json:"doc,omitempty"`
+	// Alternative names for a named type
+	Aliases []string `json:"aliases,omitempty"`
+	// Props holds any other attributes of the schema, such as BigQuery's
+	// sqlType or the precision of a decimal.
+	Props map[string]jsontext.Value `json:"-"`
+}
+
+// FullName returns the name of the type qualified by its namespace.
+func (o *SchemaObject) FullName() string {
+	if o.Namespace == "" || strings.ContainsRune(o.Name, '.') {
+		return o.Name
+	}
+	return o.Namespace + "." + o.Name
 }
 
 // SchemaRecordField represents one field of a Record schema
 type SchemaRecordField struct {
 	Name string `json:"name,omitempty"`
 	Type Schema `json:"type,omitempty"`
+	// Default is the JSON default value for the field, used when reading data
+	// written with a schema that does not have the field. Empty if the field
+	// has no default.
+	Default jsontext.Value `json:"default,omitzero"`
+	Doc     string         `json:"doc,omitempty"`
+	// Alternative names for the field. A field in data being read matches a
+	// field with one of these names if there's no field with the same name.
+	Aliases []string `json:"aliases,omitempty"`
+	// Sort order for the field: ascending, descending or ignore.
+	Order string `json:"order,omitempty"`
+	// Props holds any other attributes of the field.
+	Props map[string]jsontext.Value `json:"-"`
+}
+
+// DefaultValue returns the default value for the field decoded into the Go
+// type we'd use if decoding the field into an interface: bool, int32, int64,
+// float32, float64, []byte or string for primitives, string for enums, a byte
+// array for fixed, []any for arrays, and map[string]any for maps and records.
+// A null default is returned as nil. It is an error if the field has no
+// default.
+func (f SchemaRecordField) DefaultValue() (any, error) {
+	if len(f.Default) == 0 {
+		return nil, fmt.Errorf("field %q has no default", f.Name)
+	}
+	return defaultValue(f.Type, f.Default)
+}
+
+// schemaRecordField has the fields of SchemaRecordField without its methods,
+// so we can use the default JSON handling for the known attributes.
+type schemaRecordField SchemaRecordField
+
+var (
+	schemaObjectKeys = jsonKeys(reflect.TypeFor[SchemaObject]())
+	recordFieldKeys  = jsonKeys(reflect.TypeFor[SchemaRecordField]())
+)
+
+// jsonKeys returns the JSON names of the fields of a struct type
+func jsonKeys(typ reflect.Type) map[string]bool {
+	keys := make(map[string]bool, typ.NumField())
+	for i := range typ.NumField() {
+		if name := nameForField(typ.Field(i)); name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// extraProps returns the attributes of the JSON object raw that aren't in
+// known.
+func extraProps(raw jsontext.Value, known map[string]bool) (map[string]jsontext.Value, error) {
+	var props map[string]jsontext.Value
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return nil, err
+	}
+	for key := range props {
+		if known[key] {
+			delete(props, key)
+		}
+	}
+	if len(props) == 0 {
+		return nil, nil
+	}
+	return props, nil
+}
+
+// writeProps writes extra attributes in key order, so the output is stable.
+func writeProps(enc *jsontext.Encoder, props map[string]jsontext.Value) error {
+	for _, key := range slices.Sorted(maps.Keys(props)) {
+		if err := enc.WriteToken(jsontext.String(key)); err != nil {
+			return fmt.Errorf("writing %s key: %w", key, err)
+		}
+		if err := enc.WriteValue(props[key]); err != nil {
+			return fmt.Errorf("writing %s value: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (f *SchemaRecordField) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
+	raw, err := dec.ReadValue()
+	if err != nil {
+		return fmt.Errorf("reading record field: %w", err)
+	}
+	if err := json.Unmarshal(raw, (*schemaRecordField)(f)); err != nil {
+		return fmt.Errorf("decoding record field: %w", err)
+	}
+	if f.Props, err = extraProps(raw, recordFieldKeys); err != nil {
+		return fmt.Errorf("decoding record field properties: %w", err)
+	}
+	return nil
+}
+
+func (f *SchemaRecordField) MarshalJSONTo(enc *jsontext.Encoder) error {
+	if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+		return fmt.Errorf("writing begin object: %w", err)
+	}
+	if f.Name != "" {
+		if err := enc.WriteToken(jsontext.String("name")); err != nil {
+			return fmt.Errorf("writing name key: %w", err)
+		}
+		if err := enc.WriteToken(jsontext.String(f.Name)); err != nil {
+			return fmt.Errorf("writing name value: %w", err)
+		}
+	}
+	if err := enc.WriteToken(jsontext.String("type")); err != nil {
+		return fmt.Errorf("writing type key: %w", err)
+	}
+	if err := json.MarshalEncode(enc, &f.Type); err != nil {
+		return fmt.Errorf("encoding type: %w", err)
+	}
+	if len(f.Default) != 0 {
+		if err := enc.WriteToken(jsontext.String("default")); err != nil {
+			return fmt.Errorf("writing default key: %w", err)
+		}
+		if err := enc.WriteValue(f.Default); err != nil {
+			return fmt.Errorf("writing default value: %w", err)
+		}
+	}
+	if f.Doc != "" {
+		if err := enc.WriteToken(jsontext.String("doc")); err != nil {
+			return fmt.Errorf("writing doc key: %w", err)
+		}
+		if err := enc.WriteToken(jsontext.String(f.Doc)); err != nil {
+			return fmt.Errorf("writing doc value: %w", err)
+		}
+	}
+	if len(f.Aliases) != 0 {
+		if err := enc.WriteToken(jsontext.String("aliases")); err != nil {
+			return fmt.Errorf("writing aliases key: %w", err)
+		}
+		if err := json.MarshalEncode(enc, f.Aliases); err != nil {
+			return fmt.Errorf("encoding aliases: %w", err)
+		}
+	}
+	if f.Order != "" {
+		if err := enc.WriteToken(jsontext.String("order")); err != nil {
+			return fmt.Errorf("writing order key: %w", err)
+		}
+		if err := enc.WriteToken(jsontext.String(f.Order)); err != nil {
+			return fmt.Errorf("writing order value: %w", err)
+		}
+	}
+	if err := writeProps(enc, f.Props); err != nil {
+		return err
+	}
+	if err := enc.WriteToken(jsontext.EndObject); err != nil {
+		return fmt.Errorf("writing end object: %w", err)
+	}
+	return nil
 }
 
 func (s *Schema) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
@@ -84,15 +275,31 @@ func (s *Schema) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
 			return fmt.Errorf("decoding union: %w", err)
 		}
 	case '{':
+		raw, err := dec.ReadValue()
+		if err != nil {
+			return fmt.Errorf("reading schema object: %w", err)
+		}
 		s.Object = &SchemaObject{}
-		// do we need to isolate these decoders?
-		if err := json.UnmarshalDecode(dec, s.Object); err != nil {
-			return fmt.Errorf("decoding union: %w", err)
+		if err := json.Unmarshal(raw, s.Object); err != nil {
+			return fmt.Errorf("decoding schema object: %w", err)
+		}
+		if s.Object.Props, err = extraProps(raw, schemaObjectKeys); err != nil {
+			return fmt.Errorf("decoding schema properties: %w", err)
 		}
 
 		s.Type = s.Object.Type
 		s.Object.Type = ""
 
+		if def, ok := s.Object.Props["default"]; ok && s.Type == "enum" {
+			if err := json.Unmarshal(def, &s.Object.Default); err != nil {
+				return fmt.Errorf("decoding enum default: %w", err)
+			}
+			delete(s.Object.Props, "default")
+			if len(s.Object.Props) == 0 {
+				s.Object.Props = nil
+			}
+		}
+
 	default:
 		return fmt.Errorf("unexpected token unmarshalling schema: %s", dec.PeekKind())
 	}
@@ -135,6 +342,22 @@ func (s *Schema) MarshalJSONTo(enc *jsontext.Encoder) error {
 				return fmt.Errorf("writing namespace value: %w", err)
 			}
 		}
+		if s.Object.Doc != "" {
+			if err := enc.WriteToken(jsontext.String("doc")); err != nil {
+				return fmt.Errorf("writing doc key: %w", err)
+			}
+			if err := enc.WriteToken(jsontext.String(s.Object.Doc)); err != nil {
+				return fmt.Errorf("writing doc value: %w", err)
+			}
+		}
+		if len(s.Object.Aliases) != 0 {
+			if err := enc.WriteToken(jsontext.String("aliases")); err != nil {
+				return fmt.Errorf("writing aliases key: %w", err)
+			}
+			if err := json.MarshalEncode(enc, s.Object.Aliases); err != nil {
+				return fmt.Errorf("encoding aliases: %w", err)
+			}
+		}
 		switch s.Type {
 		case "record":
 			if err := enc.WriteToken(jsontext.String("fields")); err != nil {
@@ -150,6 +373,14 @@ func (s *Schema) MarshalJSONTo(enc *jsontext.Encoder) error {
 			if err := json.MarshalEncode(enc, s.Object.Symbols); err != nil {
 				return fmt.Errorf("encoding enum symbols: %w", err)
 			}
+			if s.Object.Default != "" {
+				if err := enc.WriteToken(jsontext.String("default")); err != nil {
+					return fmt.Errorf("writing default key: %w", err)
+				}
+				if err := enc.WriteToken(jsontext.String(s.Object.Default)); err != nil {
+					return fmt.Errorf("writing default value: %w", err)
+				}
+			}
 		case "array":
 			if err := enc.WriteToken(jsontext.String("items")); err != nil {
 				return fmt.Errorf("writing items key: %w", err)
@@ -172,6 +403,9 @@ func (s *Schema) MarshalJSONTo(enc *jsontext.Encoder) error {
 				return fmt.Errorf("writing size value: %w", err)
 			}
 		}
+		if err := writeProps(enc, s.Object.Props); err != nil {
+			return err
+		}
 		if err := enc.WriteToken(jsontext.EndObject); err != nil {
 			return fmt.Errorf("writing end object: %w", err)
 		}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -57,6 +58,76 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			schema: `{"type":"record","name":"test","fields":[{"name":"a","type":"int","default":3},{"name":"b","type":["null","string"],"default":null}]}`,
+			want: Schema{
+				Type: "record",
+				Object: &SchemaObject{
+					Name: "test",
+					Fields: []SchemaRecordField{
+						{
+							Name:    "a",
+							Type:    Schema{Type: "int"},
+							Default: jsontext.Value("3"),
+						},
+						{
+							Name: "b",
+							Type: Schema{
+								Type:  "union",
+								Union: []Schema{{Type: "null"}, {Type: "string"}},
+							},
+							Default: jsontext.Value("null"),
+						},
+					},
+				},
+			},
+		},
+		{
+			schema: `{"type":"record","name":"test","namespace":"com.example","doc":"A test","aliases":["old"],"fields":[{"name":"a","type":{"type":"string","sqlType":"JSON"},"doc":"The a","aliases":["b","c"],"order":"descending","x-other":{"z":1},"x-prop":[1,2]}],"x-custom":true}`,
+			want: Schema{
+				Type: "record",
+				Object: &SchemaObject{
+					Name:      "test",
+					Namespace: "com.example",
+					Doc:       "A test",
+					Aliases:   []string{"old"},
+					Fields: []SchemaRecordField{
+						{
+							Name: "a",
+							Type: Schema{
+								Type: "string",
+								Object: &SchemaObject{
+									Props: map[string]jsontext.Value{"sqlType": jsontext.Value(`"JSON"`)},
+								},
+							},
+							Doc:     "The a",
+							Aliases: []string{"b", "c"},
+							Order:   "descending",
+							Props: map[string]jsontext.Value{
+								"x-other": jsontext.Value(`{"z":1}`),
+								"x-prop":  jsontext.Value(`[1,2]`),
+							},
+						},
+					},
+					Props: map[string]jsontext.Value{"x-custom": jsontext.Value(`true`)},
+				},
+			},
+		},
+		{
+			schema: `{"type":"fixed","logicalType":"decimal","name":"dec","size":8,"precision":10,"scale":2}`,
+			want: Schema{
+				Type: "fixed",
+				Object: &SchemaObject{
+					LogicalType: "decimal",
+					Name:        "dec",
+					Size:        8,
+					Props: map[string]jsontext.Value{
+						"precision": jsontext.Value(`10`),
+						"scale":     jsontext.Value(`2`),
+					},
+				},
+			},
+		},
 		{
 			schema: `{"type":"enum","name":"test","symbols":["a","b"]}`,
 			want: Schema{
@@ -147,6 +218,28 @@ func TestUnmarshal(t *testing.T) {
 				Type: "string",
 			},
 		},
+		{
+			// Only enums have a default of their own. Any other default is
+			// kept as an extra property.
+			schema: `{"type":"long","default":0}`,
+			want: Schema{
+				Type: "long",
+				Object: &SchemaObject{
+					Props: map[string]jsontext.Value{"default": jsontext.Value(`0`)},
+				},
+			},
+		},
+		{
+			schema: `{"type":"enum","name":"e","symbols":["A","B"],"default":"B"}`,
+			want: Schema{
+				Type: "enum",
+				Object: &SchemaObject{
+					Name:    "e",
+					Symbols: []string{"A", "B"},
+					Default: "B",
+				},
+			},
+		},
 
 		{
 			schema: `["null","int"]`,
@@ -182,3 +275,75 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		exp    any
+	}{
+		{name: "null", schema: `{"name":"a","type":"null","default":null}`, exp: nil},
+		{name: "boolean", schema: `{"name":"a","type":"boolean","default":true}`, exp: true},
+		{name: "int", schema: `{"name":"a","type":"int","default":-7}`, exp: int32(-7)},
+		{name: "long", schema: `{"name":"a","type":"long","default":9007199254740993}`, exp: int64(9007199254740993)},
+		{name: "float", schema: `{"name":"a","type":"float","default":1.5}`, exp: float32(1.5)},
+		{name: "double", schema: `{"name":"a","type":"double","default":2.25}`, exp: 2.25},
+		{name: "bytes", schema: `{"name":"a","type":"bytes","default":"\u00ffA"}`, exp: []byte{0xff, 'A'}},
+		{name: "string", schema: `{"name":"a","type":"string","default":"hat"}`, exp: "hat"},
+		{name: "enum", schema: `{"name":"a","type":{"type":"enum","name":"e","symbols":["A","B"]},"default":"B"}`, exp: "B"},
+		{name: "fixed", schema: `{"name":"a","type":{"type":"fixed","name":"f","size":2},"default":"ab"}`, exp: [2]byte{'a', 'b'}},
+		{name: "array", schema: `{"name":"a","type":{"type":"array","items":"long"},"default":[1,2]}`, exp: []any{int64(1), int64(2)}},
+		{name: "map", schema: `{"name":"a","type":{"type":"map","values":"string"},"default":{"a":"b"}}`, exp: map[string]any{"a": "b"}},
+		{
+			name:   "record",
+			schema: `{"name":"a","type":{"type":"record","name":"r","fields":[{"name":"x","type":"int"},{"name":"y","type":"string","default":"why"}]},"default":{"x":1}}`,
+			exp:    map[string]any{"x": int32(1), "y": "why"},
+		},
+		{name: "union first", schema: `{"name":"a","type":["null","string"],"default":null}`, exp: nil},
+		{name: "union later", schema: `{"name":"a","type":["null","string"],"default":"hat"}`, exp: "hat"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var f SchemaRecordField
+			if err := json.Unmarshal([]byte(test.schema), &f); err != nil {
+				t.Fatal(err)
+			}
+			v, err := f.DefaultValue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, v); diff != "" {
+				t.Fatalf("default not as expected. %s", diff)
+			}
+		})
+	}
+}
+
+func TestDefaultValueErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "no default", schema: `{"name":"a","type":"int"}`},
+		{name: "int out of range", schema: `{"name":"a","type":"int","default":3000000000}`},
+		{name: "wrong type", schema: `{"name":"a","type":"string","default":3}`},
+		{name: "bad symbol", schema: `{"name":"a","type":{"type":"enum","name":"e","symbols":["A"]},"default":"B"}`},
+		{name: "bytes out of range", schema: `{"name":"a","type":"bytes","default":"\u0100"}`},
+		{name: "fixed size", schema: `{"name":"a","type":{"type":"fixed","name":"f","size":2},"default":"abc"}`},
+		{name: "record missing field", schema: `{"name":"a","type":{"type":"record","name":"r","fields":[{"name":"x","type":"int"}]},"default":{}}`},
+		{name: "no union branch", schema: `{"name":"a","type":["null","string"],"default":1}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var f SchemaRecordField
+			if err := json.Unmarshal([]byte(test.schema), &f); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.DefaultValue(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
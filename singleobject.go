@@ -0,0 +1,239 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// SingleObjectMagic is the marker at the start of data in AVRO single-object
+// encoding. It's followed by the little-endian CRC-64-AVRO fingerprint of the
+// writer's schema, then the AVRO binary encoding of the value.
+var SingleObjectMagic = [2]byte{0xC3, 0x01}
+
+// singleObjectHeaderLen is the length of the marker and fingerprint
+const singleObjectHeaderLen = len(SingleObjectMagic) + 8
+
+// ErrUnknownSchema is returned by a SchemaStore if it has no schema with the
+// requested fingerprint.
+var ErrUnknownSchema = errors.New("schema not found")
+
+// SchemaStore finds schemas by fingerprint. SingleObjectDecoder uses it to find
+// the schema data was written with.
+type SchemaStore interface {
+	// Schema returns the schema with the given CRC-64-AVRO fingerprint (see
+	// Schema.Fingerprint). It should return an error wrapping ErrUnknownSchema
+	// if it doesn't know the schema.
+	Schema(fingerprint uint64) (Schema, error)
+}
+
+// MemorySchemaStore is a simple SchemaStore that keeps schemas in memory. It is
+// safe for concurrent use.
+type MemorySchemaStore struct {
+	mu      sync.RWMutex
+	schemas map[uint64]Schema
+}
+
+// NewMemorySchemaStore returns a MemorySchemaStore containing the given schemas.
+func NewMemorySchemaStore(schemas ...Schema) (*MemorySchemaStore, error) {
+	s := &MemorySchemaStore{schemas: make(map[uint64]Schema, len(schemas))}
+	for _, schema := range schemas {
+		if _, err := s.Add(schema); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds a schema to the store and returns its fingerprint.
+func (s *MemorySchemaStore) Add(schema Schema) (uint64, error) {
+	fingerprint, err := schema.Fingerprint()
+	if err != nil {
+		return 0, fmt.Errorf("fingerprinting schema: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemas[fingerprint] = schema
+	return fingerprint, nil
+}
+
+// Schema returns the schema with the given fingerprint.
+func (s *MemorySchemaStore) Schema(fingerprint uint64) (Schema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	schema, ok := s.schemas[fingerprint]
+	if !ok {
+		return Schema{}, fmt.Errorf("fingerprint %016x: %w", fingerprint, ErrUnknownSchema)
+	}
+	return schema, nil
+}
+
+// SingleObjectEncoder encodes values of type T using AVRO single-object
+// encoding. This is intended for sending individual records, for example as
+// messages on a queue. It is safe for concurrent use.
+type SingleObjectEncoder[T any] struct {
+	schema Schema
+	codec  Codec
+	header [singleObjectHeaderLen]byte
+}
+
+// NewSingleObjectEncoder returns a new SingleObjectEncoder. The schema is
+// derived from T using SchemaForType. Readers will need to be able to find that
+// schema by its fingerprint.
+func NewSingleObjectEncoder[T any]() (*SingleObjectEncoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	s, err := schemaForType(typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating schema: %w", err)
+	}
+
+	c, err := buildWriteCodec(s, typ)
+	if err != nil {
+		return nil, fmt.Errorf("generating codec: %w", err)
+	}
+
+	fingerprint, err := s.Fingerprint()
+	if err != nil {
+		return nil, fmt.Errorf("fingerprinting schema: %w", err)
+	}
+
+	e := &SingleObjectEncoder[T]{
+		schema: s,
+		codec:  c,
+	}
+	copy(e.header[:], SingleObjectMagic[:])
+	binary.LittleEndian.PutUint64(e.header[len(SingleObjectMagic):], fingerprint)
+	return e, nil
+}
+
+// Schema returns the schema values are encoded with.
+func (e *SingleObjectEncoder[T]) Schema() Schema {
+	return e.schema
+}
+
+// Fingerprint returns the CRC-64-AVRO fingerprint of the schema values are
+// encoded with.
+func (e *SingleObjectEncoder[T]) Fingerprint() uint64 {
+	return binary.LittleEndian.Uint64(e.header[len(SingleObjectMagic):])
+}
+
+// Encode returns the single-object encoding of v.
+func (e *SingleObjectEncoder[T]) Encode(v *T) ([]byte, error) {
+	return e.AppendEncode(nil, v)
+}
+
+// AppendEncode appends the single-object encoding of v to buf. If v cannot be
+// encoded an error is returned along with buf unchanged.
+func (e *SingleObjectEncoder[T]) AppendEncode(buf []byte, v *T) ([]byte, error) {
+	start := len(buf)
+	out, err := appendDatum(append(buf, e.header[:]...), e.codec, unsafe.Pointer(v))
+	if err != nil {
+		return buf[:start], err
+	}
+	return out, nil
+}
+
+// SingleObjectDecoder decodes values encoded with AVRO single-object encoding
+// into values of type T. It finds the schema the data was written with in a
+// SchemaStore and resolves it against the schema for T using AVRO schema
+// resolution. It is safe for concurrent use.
+type SingleObjectDecoder[T any] struct {
+	store  SchemaStore
+	reader Schema
+
+	mu     sync.RWMutex
+	codecs map[uint64]Codec
+}
+
+// NewSingleObjectDecoder returns a new SingleObjectDecoder that looks up writer
+// schemas in store. The reader's schema is derived from T using SchemaForType
+// unless it is given via WithReaderSchema.
+func NewSingleObjectDecoder[T any](store SchemaStore, opts ...ReadOption) (*SingleObjectDecoder[T], error) {
+	var rc readConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("only structs are supported, got %v", typ)
+	}
+
+	reader := rc.reader
+	if reader.Type == "" {
+		var err error
+		if reader, err = schemaForType(typ); err != nil {
+			return nil, fmt.Errorf("building reader schema: %w", err)
+		}
+	}
+
+	return &SingleObjectDecoder[T]{
+		store:  store,
+		reader: reader,
+		codecs: make(map[uint64]Codec),
+	}, nil
+}
+
+// Decode decodes the single-object encoded data into v. Strings and byte
+// slices in v do not share memory with data.
+func (d *SingleObjectDecoder[T]) Decode(data []byte, v *T) error {
+	fingerprint, body, err := splitSingleObject(data)
+	if err != nil {
+		return err
+	}
+
+	codec, err := d.codec(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	return readDatum(codec, body, v)
+}
+
+func (d *SingleObjectDecoder[T]) codec(fingerprint uint64) (Codec, error) {
+	d.mu.RLock()
+	c, ok := d.codecs[fingerprint]
+	d.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	writer, err := d.store.Schema(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("finding writer schema: %w", err)
+	}
+
+	var t T
+	if c, err = writer.ResolvedCodec(d.reader, t); err != nil {
+		return nil, fmt.Errorf("building codec: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.codecs[fingerprint] = c
+	return c, nil
+}
+
+// SingleObjectFingerprint returns the fingerprint of the writer's schema from
+// single-object encoded data.
+func SingleObjectFingerprint(data []byte) (uint64, error) {
+	fingerprint, _, err := splitSingleObject(data)
+	return fingerprint, err
+}
+
+func splitSingleObject(data []byte) (fingerprint uint64, body []byte, err error) {
+	if len(data) < singleObjectHeaderLen {
+		return 0, nil, fmt.Errorf("data too short for single-object encoding: %d bytes", len(data))
+	}
+	if [2]byte(data) != SingleObjectMagic {
+		return 0, nil, fmt.Errorf("data does not start with single-object marker. Have %X, want %X", data[:2], SingleObjectMagic)
+	}
+	return binary.LittleEndian.Uint64(data[len(SingleObjectMagic):]), data[singleObjectHeaderLen:], nil
+}
@@ -0,0 +1,173 @@
+package avro_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/philpearl/avro"
+)
+
+func TestSingleObject(t *testing.T) {
+	type message struct {
+		ID   int64    `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+
+	enc, err := avro.NewSingleObjectEncoder[message]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := enc.Schema().Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Fingerprint() != fingerprint {
+		t.Fatalf("encoder fingerprint %x does not match schema fingerprint %x", enc.Fingerprint(), fingerprint)
+	}
+
+	in := message{ID: 42, Name: "fred", Tags: []string{"a", "b"}}
+	data, err := enc.Encode(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0] != 0xC3 || data[1] != 0x01 {
+		t.Fatalf("data does not start with marker: %X", data[:2])
+	}
+	if got := binary.LittleEndian.Uint64(data[2:10]); got != fingerprint {
+		t.Fatalf("fingerprint in data is %x, want %x", got, fingerprint)
+	}
+	if got, err := avro.SingleObjectFingerprint(data); err != nil || got != fingerprint {
+		t.Fatalf("SingleObjectFingerprint returned %x, %v", got, err)
+	}
+
+	// AppendEncode should add to the buffer
+	prefix := []byte("prefix")
+	appended, err := enc.AppendEncode(prefix, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(append([]byte("prefix"), data...), appended); diff != "" {
+		t.Fatalf("appended data not as expected (-want +got):\n%s", diff)
+	}
+
+	store, err := avro.NewMemorySchemaStore(enc.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := avro.NewSingleObjectDecoder[message](store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := message{ID: 7, Name: "overwritten"}
+	if err := dec.Decode(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, out); diff != "" {
+		t.Fatalf("decoded value not as expected (-want +got):\n%s", diff)
+	}
+
+	// A newer version of the type can read the old data
+	type newMessage struct {
+		ID      int64  `json:"id"`
+		Name    string `json:"name"`
+		Version int32  `json:"version" avro:"default=3"`
+	}
+	newDec, err := avro.NewSingleObjectDecoder[newMessage](store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newOut newMessage
+	if err := newDec.Decode(data, &newOut); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(newMessage{ID: 42, Name: "fred", Version: 3}, newOut); diff != "" {
+		t.Fatalf("decoded value not as expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestSingleObjectErrors(t *testing.T) {
+	type message struct {
+		ID int64 `json:"id"`
+	}
+
+	enc, err := avro.NewSingleObjectEncoder[message]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := enc.Encode(&message{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty, err := avro.NewMemorySchemaStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := avro.NewSingleObjectDecoder[message](empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out message
+	if err := dec.Decode(data, &out); !errors.Is(err, avro.ErrUnknownSchema) {
+		t.Fatalf("expected unknown schema error, got %v", err)
+	}
+
+	if _, err := empty.Add(enc.Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "short", data: data[:9]},
+		{name: "bad marker", data: append([]byte{0xC3, 0x02}, data[2:]...)},
+		{name: "truncated body", data: data[:len(data)-1]},
+		{name: "trailing data", data: append(data[:len(data):len(data)], 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := dec.Decode(test.data, &out); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	if err := dec.Decode(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 1 {
+		t.Fatalf("decoded ID %d, want 1", out.ID)
+	}
+}
+
+type singleObjectStringer interface{ String() string }
+
+func TestSingleObjectEncoderWriteOnly(t *testing.T) {
+	// Interface fields only need the dynamic type of their value to match the
+	// schema when writing.
+	avro.RegisterSchema(reflect.TypeFor[singleObjectStringer](), avro.Schema{Type: "long"})
+
+	type message struct {
+		D singleObjectStringer `json:"d"`
+	}
+
+	enc, err := avro.NewSingleObjectEncoder[message]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := enc.Encode(&message{D: time.Duration(21)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte{42}, data[10:]); diff != "" {
+		t.Fatalf("body not as expected (-want +got):\n%s", diff)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/go-json-experiment/json/jsontext"
 	"github.com/google/go-cmp/cmp"
 	"github.com/philpearl/avro"
 )
@@ -243,6 +244,7 @@ func TestASchema(t *testing.T) {
 							},
 						},
 					},
+					Default: jsontext.Value("null"),
 				},
 			},
 		},
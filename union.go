@@ -2,6 +2,12 @@ package avro
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
@@ -43,9 +49,32 @@ func (u *unionCodec) Omit(p unsafe.Pointer) bool {
 	return false
 }
 
+// Write writes the value using the first branch of the union that isn't null.
+// The same Go type is used for every branch, so we can't tell which branch is
+// intended. If the union includes null and the value is empty we write null
+// instead.
 func (u *unionCodec) Write(w *WriteBuf, p unsafe.Pointer) {
-	// TODO: Need a way to determine which type!
-	panic("union codec not implemented!")
+	null, value := -1, -1
+	for i, c := range u.codecs {
+		if _, ok := c.(nullCodec); ok {
+			if null < 0 {
+				null = i
+			}
+		} else if value < 0 {
+			value = i
+		}
+	}
+
+	if value >= 0 && (null < 0 || !u.codecs[value].Omit(p)) {
+		w.Varint(int64(value))
+		u.codecs[value].Write(w, p)
+		return
+	}
+	if null >= 0 {
+		w.Varint(int64(null))
+		return
+	}
+	w.SetError(fmt.Errorf("union has no branches"))
 }
 
 type unionOneAndNullCodec struct {
@@ -99,8 +128,7 @@ func (u *unionOneAndNullCodec) Omit(p unsafe.Pointer) bool {
 
 func (u *unionOneAndNullCodec) Write(w *WriteBuf, p unsafe.Pointer) {
 	if u.codec.Omit(p) {
-		// TODO: this assumes the null type is always first.
-		w.Varint(0)
+		w.Varint(int64(1 - u.nonNull))
 		return
 	}
 	w.Varint(int64(u.nonNull))
@@ -156,9 +184,352 @@ func (u *unionNullString) Omit(p unsafe.Pointer) bool {
 
 func (u *unionNullString) Write(w *WriteBuf, p unsafe.Pointer) {
 	if u.codec.Omit(p) {
-		w.Varint(0)
+		w.Varint(int64(1 - u.nonNull))
+		return
 	}
 
-	w.Varint(1)
+	w.Varint(int64(u.nonNull))
 	u.codec.Write(w, p)
 }
+
+// unionInterfaceCodec decodes a union into an interface value, using the
+// natural Go type for whichever branch is present. When encoding the dynamic
+// type of the value selects the branch of the union.
+type unionInterfaceCodec struct {
+	// unionCodec reads each branch into the interface
+	unionCodec
+	union []Schema
+	rtype reflect.Type
+	// branches caches the branch and codec to use for each dynamic type we
+	// see. It maps reflect.Type to *unionBranch
+	branches sync.Map
+}
+
+type unionBranch struct {
+	index int
+	codec Codec
+	err   error
+}
+
+func (b *codecBuilder) buildUnionInterfaceCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	c := unionInterfaceCodec{
+		unionCodec: unionCodec{codecs: make([]Codec, len(schema.Union))},
+		union:      schema.Union,
+		rtype:      typ,
+	}
+	for i, u := range schema.Union {
+		// build returns the null codec for the null branch, which leaves the
+		// interface nil.
+		sc, err := b.build(u, typ, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build union sub-codec %q: %w", u.Type, err)
+		}
+		c.codecs[i] = sc
+	}
+	return &c, nil
+}
+
+func (u *unionInterfaceCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(u.rtype)
+}
+
+func (u *unionInterfaceCodec) Omit(p unsafe.Pointer) bool {
+	return false
+}
+
+func (u *unionInterfaceCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	typ, vp := interfaceValue(u.rtype, p)
+	if typ == nil {
+		for i, s := range u.union {
+			if s.Type == "null" {
+				w.Varint(int64(i))
+				return
+			}
+		}
+		w.SetError(fmt.Errorf("union has no null branch so cannot write nil %s", u.rtype))
+		return
+	}
+
+	var b *unionBranch
+	if v, ok := u.branches.Load(typ); ok {
+		b = v.(*unionBranch)
+	} else {
+		b = &unionBranch{}
+		b.index, b.codec, b.err = selectUnionBranch(u.union, typ)
+		u.branches.Store(typ, b)
+	}
+	if b.err != nil {
+		w.SetError(b.err)
+		return
+	}
+
+	w.Varint(int64(b.index))
+	b.codec.Write(w, vp)
+}
+
+// selectUnionBranch finds the branch of the union best suited to writing values
+// of type typ. We first look for a branch that's a natural match for the type.
+// If there's none we take the first branch we can build a codec for.
+func selectUnionBranch(union []Schema, typ reflect.Type) (int, Codec, error) {
+	for i, s := range union {
+		if unionBranchMatches(s, typ) {
+			if c, err := buildWriteCodec(s, typ); err == nil {
+				return i, c, nil
+			}
+		}
+	}
+	for i, s := range union {
+		if s.Type == "null" {
+			continue
+		}
+		if c, err := buildWriteCodec(s, typ); err == nil {
+			return i, c, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("no branch of union matches type %s", typ)
+}
+
+// unionBranchMatches returns true if typ is the natural Go type for the union
+// branch s.
+func unionBranchMatches(s Schema, typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch s.Type {
+	case "boolean":
+		return typ.Kind() == reflect.Bool
+	// These match the kinds buildIntegerCodec supports.
+	case "int":
+		return typ.Kind() == reflect.Int32 || typ.Kind() == reflect.Int16
+	case "long":
+		return typ.Kind() == reflect.Int64 || typ.Kind() == reflect.Int || typ.Kind() == reflect.Uint64
+	case "float":
+		return typ.Kind() == reflect.Float32
+	case "double":
+		return typ.Kind() == reflect.Float64
+	case "string":
+		return typ.Kind() == reflect.String
+	case "bytes":
+		return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+	case "array":
+		return typ.Kind() == reflect.Slice
+	case "map":
+		return typ.Kind() == reflect.Map
+	case "record", "enum", "fixed":
+		if s.Object == nil {
+			return false
+		}
+		// The schema name may include the namespace
+		name := s.Object.Name
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		return typ.Name() == name
+	}
+	return false
+}
+
+// unionStructCodec encodes a union from a struct that has a pointer field for
+// each branch of the union. Each field is tagged with the name of its branch,
+// e.g. `avro:"branch=long"`. When writing the first non-nil field is used, and
+// when reading only the field for the branch that's present is set.
+type unionStructCodec struct {
+	rtype reflect.Type
+	// For each branch of the union, the offset of the field for that branch,
+	// or math.MaxUint64 if there's no field for the branch.
+	offsets []uintptr
+	codecs  []Codec
+	// null is the index of the null branch, or -1 if there isn't one
+	null int
+}
+
+// isUnionStruct returns true if typ is a struct with fields tagged as union
+// branches
+func isUnionStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := range typ.NumField() {
+		if _, ok := avroTagOption(typ.Field(i), "branch"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unionBranchName returns the name that identifies a branch of a union. For
+// named types this is the name of the type, otherwise it is the type itself.
+func unionBranchName(s Schema) string {
+	switch s.Type {
+	case "record", "enum", "fixed":
+		if s.Object != nil {
+			return s.Object.Name
+		}
+	}
+	return s.Type
+}
+
+func (b *codecBuilder) buildUnionStructCodec(schema Schema, typ reflect.Type) (Codec, error) {
+	branches := make(map[string]reflect.StructField, typ.NumField())
+	for i := range typ.NumField() {
+		sf := typ.Field(i)
+		name, ok := avroTagOption(sf, "branch")
+		if !ok {
+			continue
+		}
+		if name != "null" && sf.Type.Kind() != reflect.Pointer {
+			return nil, fmt.Errorf("field %s for union branch %q must be a pointer, not %s", sf.Name, name, sf.Type)
+		}
+		branches[name] = sf
+	}
+
+	c := unionStructCodec{
+		rtype:   typ,
+		offsets: make([]uintptr, len(schema.Union)),
+		codecs:  make([]Codec, len(schema.Union)),
+		null:    -1,
+	}
+	for i, u := range schema.Union {
+		c.offsets[i] = math.MaxUint64
+		name := unionBranchName(u)
+		if u.Type == "null" {
+			c.null = i
+			delete(branches, name)
+			c.codecs[i] = nullCodec{}
+			continue
+		}
+
+		var fieldType reflect.Type
+		sf, ok := branches[name]
+		if !ok && u.Object != nil {
+			name = u.Object.FullName()
+			sf, ok = branches[name]
+		}
+		if ok {
+			c.offsets[i] = sf.Offset
+			fieldType = sf.Type
+			delete(branches, name)
+		}
+
+		sc, err := b.build(u, fieldType, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build union sub-codec %q: %w", name, err)
+		}
+		c.codecs[i] = sc
+	}
+
+	if len(branches) != 0 {
+		names := slices.Sorted(maps.Keys(branches))
+		return nil, fmt.Errorf("union has no branch to match %q in %s", names, typ)
+	}
+
+	return &c, nil
+}
+
+func (u *unionStructCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := u.readIndex(r)
+	if err != nil {
+		return err
+	}
+	return u.readBranch(r, index, p)
+}
+
+func (u *unionStructCodec) readIndex(r *ReadBuf) (int64, error) {
+	index, err := r.Varint()
+	if err != nil {
+		return 0, fmt.Errorf("failed reading union selector. %w", err)
+	}
+	if index < 0 || index >= int64(len(u.codecs)) {
+		return 0, fmt.Errorf("union selector %d out of range (%d types)", index, len(u.codecs))
+	}
+	return index, nil
+}
+
+// readBranch reads the value for branch index of the union into the struct p
+// points to.
+func (u *unionStructCodec) readBranch(r *ReadBuf, index int64, p unsafe.Pointer) error {
+	if u.offsets[index] == math.MaxUint64 {
+		return u.codecs[index].Skip(r)
+	}
+	return u.codecs[index].Read(r, unsafe.Add(p, u.offsets[index]))
+}
+
+func (u *unionStructCodec) Skip(r *ReadBuf) error {
+	index, err := u.readIndex(r)
+	if err != nil {
+		return err
+	}
+	return u.codecs[index].Skip(r)
+}
+
+func (u *unionStructCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(u.rtype)
+}
+
+func (u *unionStructCodec) Omit(p unsafe.Pointer) bool {
+	return false
+}
+
+func (u *unionStructCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	for i, offset := range u.offsets {
+		if offset == math.MaxUint64 {
+			continue
+		}
+		fp := unsafe.Add(p, offset)
+		if *(*unsafe.Pointer)(fp) == nil {
+			continue
+		}
+		w.Varint(int64(i))
+		u.codecs[i].Write(w, fp)
+		return
+	}
+
+	if u.null >= 0 {
+		w.Varint(int64(u.null))
+		return
+	}
+	w.SetError(fmt.Errorf("no branch of union is set in %s", u.rtype))
+}
+
+// unionStructPointerCodec encodes a union as a pointer to a struct with a field
+// for each branch of the union. A nil pointer is the null branch.
+type unionStructPointerCodec struct {
+	*unionStructCodec
+}
+
+func (u unionStructPointerCodec) Read(r *ReadBuf, p unsafe.Pointer) error {
+	index, err := u.readIndex(r)
+	if err != nil {
+		return err
+	}
+	pp := (*unsafe.Pointer)(p)
+	if int(index) == u.null {
+		*pp = nil
+		return nil
+	}
+	if *pp == nil {
+		*pp = u.unionStructCodec.New(r)
+	}
+	return u.readBranch(r, index, *pp)
+}
+
+func (u unionStructPointerCodec) New(r *ReadBuf) unsafe.Pointer {
+	return r.Alloc(pointerType)
+}
+
+func (u unionStructPointerCodec) Omit(p unsafe.Pointer) bool {
+	return *(*unsafe.Pointer)(p) == nil
+}
+
+func (u unionStructPointerCodec) Write(w *WriteBuf, p unsafe.Pointer) {
+	pp := *(*unsafe.Pointer)(p)
+	if pp != nil {
+		u.unionStructCodec.Write(w, pp)
+		return
+	}
+	if u.null < 0 {
+		w.SetError(fmt.Errorf("union has no null branch so cannot write nil %s", u.rtype))
+		return
+	}
+	w.Varint(int64(u.null))
+}
@@ -1,8 +1,11 @@
 package avro
 
 import (
+	"reflect"
 	"testing"
 	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestUnionCodec(t *testing.T) {
@@ -193,6 +196,257 @@ func TestUnionStringCodec(t *testing.T) {
 	}
 }
 
+func TestUnionCodecWrite(t *testing.T) {
+	c := unionCodec{
+		codecs: []Codec{nullCodec{}, StringCodec{omitEmpty: true}},
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		exp  []byte
+	}{
+		{name: "null", in: "", exp: []byte{0}},
+		{name: "string", in: "foo", exp: []byte{2, 6, 'f', 'o', 'o'}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&test.in))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestUnionOneCodecNullSecond(t *testing.T) {
+	c := unionOneAndNullCodec{
+		codec:   StringCodec{omitEmpty: true},
+		nonNull: 0,
+	}
+
+	w := NewWriteBuf(nil)
+	var empty string
+	c.Write(w, unsafe.Pointer(&empty))
+	if diff := cmp.Diff([]byte{2}, w.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+var multiUnionSchema = Schema{
+	Type: "union",
+	Union: []Schema{
+		{Type: "null"},
+		{Type: "long"},
+		{Type: "string"},
+		{
+			Type: "record",
+			Object: &SchemaObject{
+				Name:      "thing",
+				Namespace: "com.example",
+				Fields: []SchemaRecordField{
+					{Name: "a", Type: Schema{Type: "long"}},
+				},
+			},
+		},
+	},
+}
+
+type thing struct {
+	A int64 `json:"a"`
+}
+
+func TestUnionInterfaceCodecWrite(t *testing.T) {
+	c, err := buildCodec(multiUnionSchema, reflect.TypeFor[any](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   any
+		exp  []byte
+	}{
+		{name: "nil", in: nil, exp: []byte{0}},
+		{name: "int64", in: int64(3), exp: []byte{2, 6}},
+		{name: "int", in: 3, exp: []byte{2, 6}},
+		{name: "int32", in: int32(3), exp: []byte{2, 6}},
+		{name: "string", in: "foo", exp: []byte{4, 6, 'f', 'o', 'o'}},
+		{name: "record", in: thing{A: 1}, exp: []byte{6, 2}},
+		{name: "record pointer", in: &thing{A: 1}, exp: []byte{6, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&test.in))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			r := NewReadBuf(w.Bytes())
+			if err := c.Skip(r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		w := NewWriteBuf(nil)
+		var v any = 3.7
+		c.Write(w, unsafe.Pointer(&v))
+		if err := w.Err(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("int or long", func(t *testing.T) {
+		// Each integer type goes to the branch it naturally matches, even
+		// though the int branch is first and could be used for all of them.
+		schema := Schema{Type: "union", Union: []Schema{{Type: "null"}, {Type: "int"}, {Type: "long"}}}
+		c, err := buildCodec(schema, reflect.TypeFor[any](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name string
+			in   any
+			exp  []byte
+		}{
+			{name: "int16", in: int16(1), exp: []byte{2, 2}},
+			{name: "int32", in: int32(1), exp: []byte{2, 2}},
+			{name: "int", in: 1, exp: []byte{4, 2}},
+			{name: "int64", in: int64(1), exp: []byte{4, 2}},
+			{name: "uint64", in: uint64(1), exp: []byte{4, 2}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				w := NewWriteBuf(nil)
+				c.Write(w, unsafe.Pointer(&test.in))
+				if err := w.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(test.exp, w.Bytes()); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		}
+	})
+}
+
+type longOrThing struct {
+	Long  *int64  `avro:"branch=long"`
+	Str   *string `avro:"branch=string"`
+	Thing *thing  `avro:"branch=thing"`
+}
+
+func TestUnionStructCodec(t *testing.T) {
+	c, err := buildCodec(multiUnionSchema, reflect.TypeFor[longOrThing](), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := int64(3)
+	str := "foo"
+	tests := []struct {
+		name string
+		in   longOrThing
+		exp  []byte
+	}{
+		{name: "null", in: longOrThing{}, exp: []byte{0}},
+		{name: "long", in: longOrThing{Long: &l}, exp: []byte{2, 6}},
+		{name: "string", in: longOrThing{Str: &str}, exp: []byte{4, 6, 'f', 'o', 'o'}},
+		{name: "record", in: longOrThing{Thing: &thing{A: 1}}, exp: []byte{6, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWriteBuf(nil)
+			c.Write(w, unsafe.Pointer(&test.in))
+			if err := w.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.exp, w.Bytes()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			var actual longOrThing
+			r := NewReadBuf(w.Bytes())
+			if err := c.Read(r, unsafe.Pointer(&actual)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.in, actual); diff != "" {
+				t.Fatal(diff)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+
+			r = NewReadBuf(w.Bytes())
+			if err := c.Skip(r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() != 0 {
+				t.Fatalf("%d bytes unread", r.Len())
+			}
+		})
+	}
+}
+
+func TestUnionStructCodecErrors(t *testing.T) {
+	t.Run("no branch set", func(t *testing.T) {
+		c, err := buildCodec(Schema{
+			Type:  "union",
+			Union: []Schema{{Type: "long"}, {Type: "string"}},
+		}, reflect.TypeFor[struct {
+			Long *int64  `avro:"branch=long"`
+			Str  *string `avro:"branch=string"`
+		}](), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWriteBuf(nil)
+		var v struct {
+			Long *int64
+			Str  *string
+		}
+		c.Write(w, unsafe.Pointer(&v))
+		if err := w.Err(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("unknown branch", func(t *testing.T) {
+		_, err := buildCodec(multiUnionSchema, reflect.TypeFor[struct {
+			Long *int64   `avro:"branch=long"`
+			F    *float64 `avro:"branch=double"`
+		}](), false)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("not a pointer", func(t *testing.T) {
+		_, err := buildCodec(multiUnionSchema, reflect.TypeFor[struct {
+			Long int64 `avro:"branch=long"`
+		}](), false)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
 func BenchmarkUnionStringCodec(b *testing.B) {
 	c := unionNullString{
 		nonNull: 1,